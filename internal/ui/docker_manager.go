@@ -4,16 +4,12 @@ package ui
 import (
     "encoding/json"
     "bufio"
-    "bytes"
     "context"
+    "errors"
     "fmt"
-    "io"
-    "os"
-    "path/filepath"
     "strings"
     "sync"
     "time"
-    "archive/tar"
     
     tea "github.com/charmbracelet/bubbletea"
     "github.com/charmbracelet/bubbles/spinner"
@@ -33,10 +29,22 @@ const (
 
 // Message types
 type (
+    buildStartedMsg struct {
+        build *registry.Build
+        start time.Time
+    }
+
+    buildEventMsg struct {
+        event registry.BuildEvent
+        build *registry.Build
+        start time.Time
+    }
+
     buildCompleteMsg struct {
         repoName string
         success  bool
         error    error
+        start    time.Time
     }
 
     containerStartedMsg struct {
@@ -180,7 +188,17 @@ func (dm *DockerManager) Update(msg tea.Msg) tea.Cmd {
             cmds = append(cmds, cmd)
         }
 
+    case buildStartedMsg:
+        cmds = append(cmds, waitForBuild(msg.build, msg.start))
+
+    case buildEventMsg:
+        dm.handleBuildEvent(msg.event)
+        cmds = append(cmds, waitForBuild(msg.build, msg.start))
+
     case buildCompleteMsg:
+        if dm.registry != nil {
+            dm.registry.Metrics.ObserveDockerOperation("build", msg.start, msg.error)
+        }
         delete(dm.spinners, msg.repoName)
         delete(dm.operations, msg.repoName)
         if msg.error != nil {
@@ -297,21 +315,45 @@ func (dm *DockerManager) ShowOperationsMenu(repoName string) tea.Cmd {
 }
 
 func (dm *DockerManager) handleMenuAction(action string) tea.Cmd {
+    if strings.HasPrefix(action, "build:") {
+        dm.menu = nil
+        return dm.buildImage(strings.TrimPrefix(action, "build:"))
+    }
+
     switch action {
     case "run":
-        return dm.runContainer
+        return dm.observe(action, dm.runContainer)
     case "build":
-        return dm.buildImage
+        return dm.chooseBuildProfile()
     case "logs":
-        return dm.viewLogs
+        return dm.observe(action, dm.viewLogs)
     case "stop":
-        return dm.stopContainer
+        return dm.observe(action, dm.stopContainer)
     case "remove":
-        return dm.removeContainer
+        return dm.observe(action, dm.removeContainer)
+    case "cancel":
+        dm.menu = nil
     }
     return nil
 }
 
+// observe records the duration and result of a Docker operation in the
+// registry metrics.
+func (dm *DockerManager) observe(operation string, op tea.Cmd) tea.Cmd {
+    return func() tea.Msg {
+        start := time.Now()
+        msg := op()
+        var err error
+        if result, ok := msg.(dockerMsg); ok && result.Type == MsgTypeError {
+            err = errors.New(result.Message)
+        }
+        if dm.registry != nil {
+            dm.registry.Metrics.ObserveDockerOperation(operation, start, err)
+        }
+        return msg
+    }
+}
+
 // In docker_manager.go, add these implementations:
 
 // Docker operations implementation
@@ -325,9 +367,17 @@ func (dm *DockerManager) runContainer() tea.Msg {
         }
     }
 
+    item, err := dm.registry.Item(dm.activeRepo)
+    if err != nil {
+        return dockerMsg{
+            Type:    MsgTypeError,
+            Message: fmt.Sprintf("Failed to find repository: %v", err),
+        }
+    }
+
     // Create container configuration
     config := &container.Config{
-        Image: dm.activeRepo + ":latest",
+        Image: item.Manifest.ImageRepository(dm.activeRepo) + ":latest",
         Tty:   true,
     }
 
@@ -361,53 +411,84 @@ func (dm *DockerManager) runContainer() tea.Msg {
     }
 }
 
-func (dm *DockerManager) buildImage() tea.Msg {
-    ctx := context.Background()
-
+// chooseBuildProfile builds the active repository's image straight away
+// unless its manifest declares build profiles to choose from
+func (dm *DockerManager) chooseBuildProfile() tea.Cmd {
     if dm.activeRepo == "" {
-        return dockerMsg{
-            Type:    MsgTypeError,
-            Message: "No repository selected",
-        }
+        return dm.showError(errors.New("No repository selected"))
     }
 
-    // Create build context tar
-    buildCtx, err := createBuildContext(dm.activeRepo)
+    profiles, err := dm.registry.BuildProfiles(dm.activeRepo)
     if err != nil {
-        return dockerMsg{
-            Type:    MsgTypeError,
-            Message: fmt.Sprintf("Failed to create build context: %v", err),
-        }
+        return dm.showError(err)
+    }
+    if len(profiles) == 0 {
+        return dm.buildImage("")
     }
+    dm.menu = BuildProfilesMenu(dm.activeRepo, profiles)
+    return nil
+}
 
-    // Build options
-    options := types.ImageBuildOptions{
-        Tags:       []string{dm.activeRepo + ":latest"},
-        Dockerfile: "Dockerfile",
+// buildImage starts building the active repository's image with profile
+// off the update loop; its events arrive as buildEventMsg until a
+// buildCompleteMsg ends the build
+func (dm *DockerManager) buildImage(profile string) tea.Cmd {
+    if dm.activeRepo == "" {
+        return dm.showError(errors.New("No repository selected"))
     }
 
-    // Build the image
-    response, err := dm.client.ImageBuild(ctx, buildCtx, options)
-    if err != nil {
-        return dockerMsg{
-            Type:    MsgTypeError,
-            Message: fmt.Sprintf("Failed to build image: %v", err),
+    repoName := dm.activeRepo
+    dm.startOperation(repoName, "Building "+repoName)
+    dm.logs[repoName] = ""
+    return func() tea.Msg {
+        start := time.Now()
+        build, err := dm.registry.BuildImage(context.Background(), repoName, profile)
+        if err != nil {
+            return buildCompleteMsg{repoName: repoName, error: err, start: start}
         }
+        return buildStartedMsg{build: build, start: start}
     }
-    defer response.Body.Close()
+}
 
-    // Read build output
-    var output strings.Builder
-    scanner := bufio.NewScanner(response.Body)
-    for scanner.Scan() {
-        output.WriteString(scanner.Text() + "\n")
+// waitForBuild delivers the next event of a build running since start
+func waitForBuild(build *registry.Build, start time.Time) tea.Cmd {
+    return func() tea.Msg {
+        event, ok := <-build.Events()
+        if !ok {
+            _, err := build.Wait()
+            return buildCompleteMsg{repoName: build.Repo, success: err == nil, error: err, start: start}
+        }
+        return buildEventMsg{event: event, build: build, start: start}
     }
+}
 
-    return dockerMsg{
-        Type:    MsgTypeSuccess,
-        Message: "Image built successfully",
-        Data:    output.String(),
-    }
+// handleBuildEvent shows a build's progress next to its spinner and adds
+// its output to the repository's log viewport
+func (dm *DockerManager) handleBuildEvent(event registry.BuildEvent) {
+    dm.mu.Lock()
+    defer dm.mu.Unlock()
+
+    var line string
+    switch event.Type {
+    case registry.BuildStep:
+        dm.operations[event.Repo] = fmt.Sprintf("Building %s (step %d/%d)", event.Repo, event.Step, event.Steps)
+        line = fmt.Sprintf("Step %d/%d: %s", event.Step, event.Steps, event.Message)
+    case registry.BuildCached:
+        line = "  (cached)"
+    case registry.BuildOutput, registry.BuildError:
+        line = "  " + event.Message
+    case registry.BuildImageID:
+        line = "Built " + event.ImageID
+    }
+
+    dm.logs[event.Repo] += line + "\n"
+    vp, ok := dm.viewports[event.Repo]
+    if !ok {
+        vp = viewport.New(dm.width, 10)
+    }
+    vp.SetContent(dm.logs[event.Repo])
+    vp.GotoBottom()
+    dm.viewports[event.Repo] = vp
 }
 
 func (dm *DockerManager) viewLogs() tea.Msg {
@@ -511,63 +592,6 @@ func (dm *DockerManager) handleDockerMessage(msg dockerMsg) tea.Cmd {
     return nil
 }
 
-// Helper function to create build context
-func createBuildContext(repoPath string) (io.Reader, error) {
-    var buf bytes.Buffer
-    tw := tar.NewWriter(&buf)
-
-    // Walk through the repository directory
-    err := filepath.Walk(repoPath, func(path string, info os.FileInfo, err error) error {
-        if err != nil {
-            return err
-        }
-
-        // Create tar header
-        header, err := tar.FileInfoHeader(info, info.Name())
-        if err != nil {
-            return err
-        }
-
-        // Update header name to be relative to repo path
-        relPath, err := filepath.Rel(repoPath, path)
-        if err != nil {
-            return err
-        }
-        header.Name = relPath
-
-        // Write header
-        if err := tw.WriteHeader(header); err != nil {
-            return err
-        }
-
-        // If not a directory, write file content
-        if !info.IsDir() {
-            file, err := os.Open(path)
-            if err != nil {
-                return err
-            }
-            defer file.Close()
-
-            if _, err := io.Copy(tw, file); err != nil {
-                return err
-            }
-        }
-
-        return nil
-    })
-
-    if err != nil {
-        return nil, err
-    }
-
-    // Close tar writer
-    if err := tw.Close(); err != nil {
-        return nil, err
-    }
-
-    return &buf, nil
-}
-
 func (dm *DockerManager) SelectContainer(containerID string) {
     dm.mu.Lock()
     defer dm.mu.Unlock()
@@ -129,6 +129,18 @@ func DockerOperationsMenu(repoName string) *Menu {
     return NewMenu("Docker Operations: "+repoName, items, "docker")
 }
 
+// BuildProfilesMenu offers the default build and each named build profile
+func BuildProfilesMenu(repoName string, profiles []string) *Menu {
+    items := []MenuItem{
+        {Title: "Default", Icon: "📦", Action: "build:", Description: "Settings at the top of the docker section"},
+    }
+    for _, profile := range profiles {
+        items = append(items, MenuItem{Title: profile, Icon: "📦", Action: "build:" + profile})
+    }
+    items = append(items, MenuItem{Title: "Cancel", Icon: "❌", Action: "cancel"})
+    return NewMenu("Build Profile: "+repoName, items, "docker")
+}
+
 func ContainerActionsMenu() *Menu {
     items := []MenuItem{
         {Title: "View Details", Icon: "🔍", Action: "details"},
@@ -1,6 +1,11 @@
 // File: internal/ui/messages.go
 package ui
 
+import (
+    "github.com/Cdaprod/go-middleware-registry/registry"
+    tea "github.com/charmbracelet/bubbletea"
+)
+
 // Message types for various UI components
 type (
     // Docker-related messages
@@ -52,6 +57,27 @@ type (
         Type    string
         Message string
     }
+
+    // Health report for the Health tab
+    healthMsg struct {
+        Report registry.HealthReport
+    }
+
+    // Commit timeline for the Activity tab
+    activityMsg struct {
+        Entries []registry.LogEntry
+    }
+
+    // Event published by the registry
+    registryEventMsg struct {
+        Event registry.Event
+    }
+
+    // Progress of a running repository scan
+    scanProgressMsg struct {
+        Progress registry.DiscoveryProgress
+        updates  <-chan tea.Msg
+    }
 )
 
 // Message type constants
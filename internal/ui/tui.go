@@ -2,6 +2,7 @@
 package ui
 
 import (
+    "context"
     "fmt"
     "strings"
     "time"
@@ -64,6 +65,10 @@ type model struct {
     errorMsg   string
     successMsg string
     loading    bool
+    scanStatus string
+
+    // Registry events, such as repositories found by the watcher
+    events chan registry.Event
 }
 
 // List item implementation
@@ -93,6 +98,8 @@ func NewModel(reg *registry.Registry) (model, error) {
             "Repositories",
             "Docker",
             "Configurations",
+            "Health",
+            "Activity",
         },
         registry:      reg,
         dockerManager: dockerManager,
@@ -104,6 +111,15 @@ func NewModel(reg *registry.Registry) (model, error) {
     m.lists = make([]list.Model, len(m.Tabs))
     m.initializeLists()
 
+    // Forward registry events, dropping them when the UI falls behind
+    m.events = make(chan registry.Event, 16)
+    reg.Subscribe(func(e registry.Event) {
+        select {
+        case m.events <- e:
+        default:
+        }
+    })
+
     return m, nil
 }
 
@@ -119,31 +135,81 @@ func (m *model) initializeLists() {
     m.lists[0] = createList(registrarItems, "Registrar Operations")
 
     // Repositories
+    m.lists[1] = createList(repositoryItems(m.registry), "Repositories")
+
+    // Docker Operations (will be populated dynamically)
+    m.lists[2] = createList([]list.Item{}, "Docker Operations")
+
+    // Configurations
+    configItems := []list.Item{
+        listItem{title: "Global Settings", desc: "Configure global registry settings"},
+        listItem{title: "Docker Settings", desc: "Configure Docker integration settings"},
+        listItem{title: "Export Data", desc: "Export registry data"},
+    }
+    m.lists[3] = createList(configItems, "Configurations")
+
+    // Health (populated by loadHealth)
+    m.lists[4] = createList([]list.Item{}, "Health")
+
+    // Activity (populated by loadActivity)
+    m.lists[5] = createList([]list.Item{}, "Activity")
+}
+
+// repositoryItems lists the registered repositories
+func repositoryItems(reg *registry.Registry) []list.Item {
     var repoItems []list.Item
-    for _, item := range m.registry.ListItems() {
+    for _, item := range reg.ListItems() {
         icon := "📁"
         if item.HasDockerfile {
             icon = "🐳"
         } else if item.GitRepo != nil {
             icon = "󰊤"
         }
+        desc := item.Path
+        if item.GitStatus != nil {
+            desc = fmt.Sprintf("%s  %s", item.GitStatus.Summary(), item.Path)
+        }
         repoItems = append(repoItems, listItem{
             title: fmt.Sprintf("%s %s", icon, item.Name),
-            desc:  item.Path,
+            desc:  desc,
         })
     }
-    m.lists[1] = createList(repoItems, "Repositories")
+    return repoItems
+}
 
-    // Docker Operations (will be populated dynamically)
-    m.lists[2] = createList([]list.Item{}, "Docker Operations")
+// activityItems turns timeline entries into list items, newest first
+func activityItems(entries []registry.LogEntry) []list.Item {
+    items := make([]list.Item, 0, len(entries))
+    for _, entry := range entries {
+        items = append(items, listItem{
+            title: fmt.Sprintf("%s %s  %s", entry.ShortHash(), entry.Repo, entry.Subject),
+            desc:  fmt.Sprintf("%s • %s", entry.Author, entry.When.Format("2006-01-02 15:04")),
+        })
+    }
+    return items
+}
 
-    // Configurations
-    configItems := []list.Item{
-        listItem{title: "Global Settings", desc: "Configure global registry settings"},
-        listItem{title: "Docker Settings", desc: "Configure Docker integration settings"},
-        listItem{title: "Export Data", desc: "Export registry data"},
+// healthItems turns a health report into list items, one per repository
+func healthItems(report registry.HealthReport) []list.Item {
+    items := []list.Item{
+        listItem{
+            title: fmt.Sprintf("Overall %d%%", report.Score),
+            desc:  fmt.Sprintf("%d repositories checked at %s", len(report.Repos), report.CheckedAt.Format("15:04:05")),
+        },
     }
-    m.lists[3] = createList(configItems, "Configurations")
+    for _, repo := range report.Repos {
+        desc := "all checks passed"
+        if repo.Error != "" {
+            desc = repo.Error
+        } else if failed := repo.Failed(); len(failed) > 0 {
+            desc = "missing: " + strings.Join(failed, ", ")
+        }
+        items = append(items, listItem{
+            title: fmt.Sprintf("%3d%% %s", repo.Score, repo.Repo),
+            desc:  desc,
+        })
+    }
+    return items
 }
 
 func createList(items []list.Item, title string) list.Model {
@@ -158,6 +224,9 @@ func (m model) Init() tea.Cmd {
     return tea.Batch(
         m.spinner.Tick,
         checkDockerStatus(m.registry),
+        loadHealth(m.registry),
+        loadActivity(m.registry),
+        waitForEvent(m.events),
     )
 }
 
@@ -211,6 +280,23 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
     case clearMessageMsg:
         m.errorMsg = ""
         m.successMsg = ""
+
+    case healthMsg:
+        m.lists[4].SetItems(healthItems(msg.Report))
+
+    case activityMsg:
+        m.lists[5].SetItems(activityItems(msg.Entries))
+
+    case registryEventMsg:
+        switch msg.Event.Type {
+        case registry.EventRepoAdded, registry.EventRepoRemoved, registry.EventRepoChanged:
+            m.lists[1].SetItems(repositoryItems(m.registry))
+        }
+        cmds = append(cmds, waitForEvent(m.events))
+
+    case scanProgressMsg:
+        m.scanStatus = fmt.Sprintf("scanned %d directories, found %d repositories", msg.Progress.Dirs, msg.Progress.Found)
+        cmds = append(cmds, waitForScan(msg.updates))
     }
 
     // Update active list
@@ -283,6 +369,9 @@ func (m model) View() string {
 
     if m.loading {
         mainContent = fmt.Sprintf("%s Loading...", m.spinner.View())
+        if m.scanStatus != "" {
+            mainContent = fmt.Sprintf("%s Scanning: %s", m.spinner.View(), m.scanStatus)
+        }
     }
 
     b.WriteString(windowStyle.
@@ -339,6 +428,10 @@ func (m *model) handleSelection() []tea.Cmd {
         cmds = append(cmds, m.handleDockerOperation(item))
     case 3: // Configurations
         cmds = append(cmds, m.handleConfigOperation(item))
+    case 4: // Health
+        cmds = append(cmds, loadHealth(m.registry))
+    case 5: // Activity
+        cmds = append(cmds, loadActivity(m.registry))
     }
     
     return cmds
@@ -371,6 +464,10 @@ func (m model) clearMessageAfterDelay() tea.Cmd {
 // Operation handlers
 func (m *model) handleRegistrarOperation(operation string) tea.Cmd {
     m.loading = true
+    if operation == "Scan Projects" {
+        m.scanStatus = ""
+        return scanProjects(m.registry)
+    }
     return func() tea.Msg {
         var success bool
         var message string
@@ -380,12 +477,26 @@ func (m *model) handleRegistrarOperation(operation string) tea.Cmd {
             // Implementation
             success = true
             message = "Repository added successfully"
-        case "Scan Projects":
-            err := m.registry.ScanRepositories()
+        case "Toggle Repository":
+            ctx, cancel := context.WithTimeout(context.Background(), registry.DefaultAskTimeout)
+            defer cancel()
+            result, err := m.registry.ToggleRepo(ctx, m.selectedRepoName())
             success = err == nil
-            message = "Scan completed"
-            if !success {
-                message = fmt.Sprintf("Scan failed: %v", err)
+            if success {
+                message = fmt.Sprintf("Repository %s enabled: %v", result.Name, result.Active)
+            } else {
+                message = fmt.Sprintf("Toggle failed: %v", err)
+            }
+        case "Configure Repository":
+            ctx, cancel := context.WithTimeout(context.Background(), registry.DefaultAskTimeout)
+            defer cancel()
+            result, err := m.registry.ConfigureRepo(ctx, m.selectedRepoName())
+            success = err == nil
+            if success {
+                message = fmt.Sprintf("Repository %s configured (Dockerfile: %v, Pipeline: %v)",
+                    result.Name, result.IsDocker, result.HasPipeline)
+            } else {
+                message = fmt.Sprintf("Configure failed: %v", err)
             }
         }
         
@@ -393,6 +504,17 @@ func (m *model) handleRegistrarOperation(operation string) tea.Cmd {
     }
 }
 
+// selectedRepoName returns the repository highlighted in the Repositories tab
+func (m *model) selectedRepoName() string {
+    selected := m.lists[1].SelectedItem()
+    if selected == nil {
+        return ""
+    }
+    // Titles are rendered as "<icon> <name>"
+    parts := strings.SplitN(selected.(listItem).title, " ", 2)
+    return parts[len(parts)-1]
+}
+
 func (m *model) handleRepositorySelection(item listItem) tea.Cmd {
     if strings.Contains(item.title, "🐳") {
         m.activeRepo = strings.TrimPrefix(item.title, "🐳 ")
@@ -423,6 +545,70 @@ func LaunchTUI(reg *registry.Registry) error {
     return err
 }
 
+// loadHealth scores every repository in the background
+func loadHealth(reg *registry.Registry) tea.Cmd {
+    return func() tea.Msg {
+        ctx, cancel := context.WithTimeout(context.Background(), registry.DefaultAskTimeout)
+        defer cancel()
+        return healthMsg{Report: reg.Health(ctx)}
+    }
+}
+
+// activityWindow is how far back the Activity tab looks
+const activityWindow = 14 * 24 * time.Hour
+
+// loadActivity reads the recent commit timeline in the background
+func loadActivity(reg *registry.Registry) tea.Cmd {
+    return func() tea.Msg {
+        ctx, cancel := context.WithTimeout(context.Background(), registry.DefaultAskTimeout)
+        defer cancel()
+        entries, _ := reg.Log(ctx, registry.LogOptions{Since: time.Now().Add(-activityWindow), Limit: 500})
+        return activityMsg{Entries: entries}
+    }
+}
+
+// waitForEvent delivers the next registry event
+func waitForEvent(events <-chan registry.Event) tea.Cmd {
+    return func() tea.Msg {
+        return registryEventMsg{Event: <-events}
+    }
+}
+
+// scanProjects scans the projects directory in the background, streaming
+// progress as scanProgressMsg until an operationCompleteMsg ends the scan
+func scanProjects(reg *registry.Registry) tea.Cmd {
+    updates := make(chan tea.Msg, 1)
+    opts := reg.Config.Discovery
+    opts.Progress = func(p registry.DiscoveryProgress) {
+        // Drop updates the UI has not caught up with
+        select {
+        case updates <- scanProgressMsg{Progress: p}:
+        default:
+        }
+    }
+    go func() {
+        found, err := reg.Scan(context.Background(), opts)
+        if err != nil {
+            updates <- operationCompleteMsg{success: false, message: fmt.Sprintf("Scan failed: %v", err)}
+            return
+        }
+        updates <- operationCompleteMsg{success: true, message: fmt.Sprintf("Scan completed: %d repositories found", found)}
+    }()
+    return waitForScan(updates)
+}
+
+// waitForScan delivers the next message of a running scan
+func waitForScan(updates <-chan tea.Msg) tea.Cmd {
+    return func() tea.Msg {
+        msg := <-updates
+        if progress, ok := msg.(scanProgressMsg); ok {
+            progress.updates = updates
+            return progress
+        }
+        return msg
+    }
+}
+
 // Helper function to check Docker status
 func checkDockerStatus(reg *registry.Registry) tea.Cmd {
     return func() tea.Msg {
@@ -2,10 +2,16 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
+	"sort"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/Cdaprod/go-middleware-registry/internal/ui"
 	"github.com/Cdaprod/go-middleware-registry/registry"
@@ -15,6 +21,13 @@ import (
 // Global Registry instance
 var globalRegistry *registry.Registry
 
+// shutdownTimeout bounds how long the registry may take to shut down.
+const shutdownTimeout = 15 * time.Second
+
+// logTimeout bounds how long the log command waits for commit histories. The
+// first read of a repository's history may walk many commits.
+const logTimeout = 2 * time.Minute
+
 // Root command for the CLI application.
 var rootCmd = &cobra.Command{
 	Use:   "registry",
@@ -30,6 +43,9 @@ var listCmd = &cobra.Command{
 			fmt.Println("Registry not initialized.")
 			os.Exit(1)
 		}
+		ctx, cancel := context.WithTimeout(context.Background(), registry.DefaultAskTimeout)
+		globalRegistry.RefreshAllStatus(ctx)
+		cancel()
 		items := globalRegistry.ListItems()
 		displayTable(items)
 	},
@@ -38,13 +54,64 @@ var listCmd = &cobra.Command{
 var scanCmd = &cobra.Command{
 	Use:   "scan",
 	Short: "Scan projects directory for repositories",
+	Long:  "Scan the projects directory for repositories. Directories listed in a .registryignore file (gitignore syntax) are skipped, and the search stops at each repository found apart from its submodules.",
 	Run: func(cmd *cobra.Command, args []string) {
 		if globalRegistry == nil {
 			fmt.Println("Registry not initialized.")
 			os.Exit(1)
 		}
-		globalRegistry.Actor.MsgChan <- ScanDir{Directory: globalRegistry.Config.ProjectsPath}
+		opts := globalRegistry.Config.Discovery
+		if cmd.Flags().Changed("depth") {
+			opts.MaxDepth, _ = cmd.Flags().GetInt("depth")
+		}
+		if cmd.Flags().Changed("include") {
+			opts.Include, _ = cmd.Flags().GetStringSlice("include")
+		}
+		if cmd.Flags().Changed("exclude") {
+			opts.Exclude, _ = cmd.Flags().GetStringSlice("exclude")
+		}
+		if cmd.Flags().Changed("nested") {
+			opts.Nested, _ = cmd.Flags().GetBool("nested")
+		}
+		opts.Progress = func(p registry.DiscoveryProgress) {
+			fmt.Printf("\rScanned %d directories, found %d repositories", p.Dirs, p.Found)
+			if p.Done {
+				fmt.Println()
+			}
+		}
+
 		fmt.Printf("Scan initiated for directory: %s\n", globalRegistry.Config.ProjectsPath)
+		found, err := globalRegistry.Scan(context.Background(), opts)
+		if err != nil {
+			fmt.Printf("Error scanning repositories: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Scan complete: %d repositories found\n", found)
+	},
+}
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Keep the registry in sync with the projects directory until interrupted",
+	Run: func(cmd *cobra.Command, args []string) {
+		if globalRegistry == nil {
+			fmt.Println("Registry not initialized.")
+			os.Exit(1)
+		}
+		debounce, _ := cmd.Flags().GetDuration("debounce")
+		globalRegistry.Subscribe(func(e registry.Event) {
+			switch e.Type {
+			case registry.EventRepoAdded, registry.EventRepoRemoved, registry.EventRepoChanged:
+				fmt.Printf("%s %-13s %s\n", e.Time.Format("15:04:05"), e.Type, e.Actor)
+			}
+		})
+		if err := globalRegistry.Watch(debounce); err != nil {
+			fmt.Printf("Error watching projects directory: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Watching %s, press Ctrl+C to stop\n", globalRegistry.Config.ProjectsPath)
+		// The signal handler shuts the registry down and exits.
+		select {}
 	},
 }
 
@@ -58,8 +125,11 @@ var infoCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		item, exists := globalRegistry.Actor.Repos[args[0]]
-		if !exists {
+		ctx, cancel := context.WithTimeout(context.Background(), registry.DefaultAskTimeout)
+		globalRegistry.RefreshStatus(ctx, args[0])
+		cancel()
+		item, err := globalRegistry.Item(args[0])
+		if err != nil {
 			fmt.Printf("Repository '%s' not found\n", args[0])
 			os.Exit(1)
 		}
@@ -68,6 +138,143 @@ var infoCmd = &cobra.Command{
 	},
 }
 
+var buildCmd = &cobra.Command{
+	Use:   "build [repository]",
+	Short: "Build the Docker image of a repository",
+	Long:  "Build the Docker image of a repository. The Dockerfile, context directory, target stage, build args, labels and cache settings come from the docker section of its .registry.yaml, and --profile applies one of the named profiles declared under docker.profiles on top of them. The image is tagged from the repository's Git state using the docker.tags templates, by default the short commit hash, the branch and the nearest semver tag, each with a -dirty suffix for a dirty tree, and latest.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if globalRegistry == nil {
+			fmt.Println("Registry not initialized.")
+			os.Exit(1)
+		}
+
+		profile, _ := cmd.Flags().GetString("profile")
+		build, err := globalRegistry.BuildImage(context.Background(), args[0], profile)
+		if err != nil {
+			fmt.Printf("Error building image: %v\n", err)
+			if errors.Is(err, registry.ErrUnknownProfile) {
+				profiles, _ := globalRegistry.BuildProfiles(args[0])
+				fmt.Printf("Available profiles: %s\n", strings.Join(profiles, ", "))
+			}
+			os.Exit(1)
+		}
+		for event := range build.Events() {
+			switch event.Type {
+			case registry.BuildStep:
+				fmt.Printf("Step %d/%d: %s\n", event.Step, event.Steps, event.Message)
+			case registry.BuildCached:
+				fmt.Println("  (cached)")
+			case registry.BuildOutput:
+				fmt.Printf("  %s\n", event.Message)
+			}
+		}
+		imageID, err := build.Wait()
+		if err != nil {
+			fmt.Printf("Error building image: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Built %s\n", imageID)
+		for _, tag := range build.Tags {
+			fmt.Printf("Tagged %s\n", tag)
+		}
+	},
+}
+
+var pushCmd = &cobra.Command{
+	Use:   "push [repository]",
+	Short: "Push the images the registry built for a repository",
+	Long:  "Push the tags of the newest image the registry built for a repository, or of every image it built with --all, to the container registry set by --target or REGISTRY_PUSH_TARGET. Credentials come from REGISTRY_PUSH_USERNAME and REGISTRY_PUSH_PASSWORD when set, otherwise from the Docker config and its credential helpers. Failed pushes are retried with backoff.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if globalRegistry == nil {
+			fmt.Println("Registry not initialized.")
+			os.Exit(1)
+		}
+
+		if target, _ := cmd.Flags().GetString("target"); target != "" {
+			globalRegistry.Config.PushTarget = target
+		}
+		if retries, _ := cmd.Flags().GetInt("retries"); cmd.Flags().Changed("retries") {
+			globalRegistry.Config.PushPolicy.Attempts = retries + 1
+		}
+		all, _ := cmd.Flags().GetBool("all")
+		push, err := globalRegistry.PushImage(context.Background(), args[0], all)
+		if err != nil {
+			fmt.Printf("Error pushing images: %v\n", err)
+			os.Exit(1)
+		}
+		// A layer's byte count is redrawn in place until another line is
+		// printed.
+		progress := false
+		for event := range push.Events() {
+			layer := event.Type == registry.PushProgress && event.Layer != ""
+			if progress && !layer {
+				fmt.Println()
+			}
+			progress = layer && event.Total > 0
+			switch event.Type {
+			case registry.PushStarted:
+				fmt.Printf("Pushing %s\n", event.Ref)
+			case registry.PushProgress:
+				if event.Layer == "" {
+					fmt.Printf("  %s\n", event.Status)
+				} else if event.Total > 0 {
+					fmt.Printf("\r\033[K  %s: %s %d/%d", event.Layer, event.Status, event.Current, event.Total)
+				} else {
+					fmt.Printf("\r\033[K  %s: %s\n", event.Layer, event.Status)
+				}
+			case registry.PushRetrying:
+				fmt.Printf("  attempt %d failed: %s, retrying in %s\n", event.Attempt, event.Status, event.Backoff)
+			case registry.PushPushed:
+				fmt.Printf("Pushed %s@%s\n", event.Ref, event.Digest)
+			case registry.PushFailed:
+				fmt.Printf("Failed to push %s: %s\n", event.Ref, event.Status)
+			}
+		}
+		if progress {
+			fmt.Println()
+		}
+		if _, err := push.Wait(); err != nil {
+			fmt.Printf("Error pushing images: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var imagesCmd = &cobra.Command{
+	Use:   "images [repository]",
+	Short: "List the images the registry built for a repository",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if globalRegistry == nil {
+			fmt.Println("Registry not initialized.")
+			os.Exit(1)
+		}
+
+		info, err := globalRegistry.GetDockerInfo(args[0])
+		if err != nil {
+			fmt.Printf("Error reading images of '%s': %v\n", args[0], err)
+			os.Exit(1)
+		}
+		if len(info.History) == 0 {
+			fmt.Printf("No images built for '%s'\n", args[0])
+			return
+		}
+		for _, image := range info.History {
+			commit := image.Commit
+			if len(commit) > 7 {
+				commit = commit[:7]
+			}
+			id := strings.TrimPrefix(image.ID, "sha256:")
+			if len(id) > 12 {
+				id = id[:12]
+			}
+			fmt.Printf("%s  %s  %-7s  %s\n", id, image.Created.Format("2006-01-02 15:04:05"), commit, strings.Join(image.Tags, ", "))
+		}
+	},
+}
+
 var interactiveCmd = &cobra.Command{
 	Use:   "interactive",
 	Short: "Launch interactive TUI",
@@ -77,6 +284,10 @@ var interactiveCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		// Keep the views current while the TUI is open.
+		if err := globalRegistry.Watch(0); err != nil {
+			fmt.Printf("Error watching projects directory: %v\n", err)
+		}
 		if err := ui.LaunchTUI(globalRegistry); err != nil {
 			fmt.Printf("Error starting TUI: %v\n", err)
 			os.Exit(1)
@@ -94,8 +305,46 @@ var toggleCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		globalRegistry.Actor.MsgChan <- ToggleRepo{Name: args[0]}
-		fmt.Printf("Toggle command sent for repository: %s\n", args[0])
+		ctx, cancel := context.WithTimeout(context.Background(), registry.DefaultAskTimeout)
+		defer cancel()
+
+		result, err := globalRegistry.ToggleRepo(ctx, args[0])
+		if err != nil {
+			fmt.Printf("Error toggling repository '%s': %v\n", args[0], err)
+			os.Exit(1)
+		}
+
+		status := "disabled"
+		if result.Active {
+			status = "enabled"
+		}
+		fmt.Printf("Repository '%s' is now %s\n", result.Name, status)
+	},
+}
+
+// Command to register a local repository or clone one from a URL.
+var addCmd = &cobra.Command{
+	Use:   "add [name] [path or url]",
+	Short: "Add a repository, cloning it into the projects path when given a URL",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		if globalRegistry == nil {
+			fmt.Println("Registry not initialized.")
+			os.Exit(1)
+		}
+
+		branch, _ := cmd.Flags().GetString("branch")
+		depth, _ := cmd.Flags().GetInt("depth")
+		item, err := globalRegistry.AddRepo(context.Background(), args[0], args[1],
+			registry.WithCloneBranch(branch),
+			registry.WithCloneDepth(depth),
+			registry.WithCloneProgress(os.Stdout),
+		)
+		if err != nil {
+			fmt.Printf("Error adding repository '%s': %v\n", args[0], err)
+			os.Exit(1)
+		}
+		displayRepoInfo(item)
 	},
 }
 
@@ -109,23 +358,400 @@ var configureCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		globalRegistry.Actor.MsgChan <- ConfigureRepo{Name: args[0]}
-		fmt.Printf("Configure command sent for repository: %s\n", args[0])
+		ctx, cancel := context.WithTimeout(context.Background(), registry.DefaultAskTimeout)
+		defer cancel()
+
+		result, err := globalRegistry.ConfigureRepo(ctx, args[0])
+		if err != nil {
+			fmt.Printf("Error configuring repository '%s': %v\n", args[0], err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Repository '%s' configured:\n", result.Name)
+		fmt.Printf("  Dockerfile: %v\n", result.IsDocker)
+		fmt.Printf("  Pipeline:   %v\n", result.HasPipeline)
+	},
+}
+
+var historyCmd = &cobra.Command{
+	Use:   "history [repository]",
+	Short: "Show the recorded history of changes to the registry",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if globalRegistry == nil {
+			fmt.Println("Registry not initialized.")
+			os.Exit(1)
+		}
+
+		repo := ""
+		if len(args) == 1 {
+			repo = args[0]
+		}
+
+		entries, err := globalRegistry.History(repo)
+		if err != nil {
+			fmt.Printf("Error reading history: %v\n", err)
+			os.Exit(1)
+		}
+		displayHistory(entries)
+	},
+}
+
+// Command to configure repositories in dependency order.
+var runCmd = &cobra.Command{
+	Use:   "run [repository...]",
+	Short: "Configure repositories in dependency order",
+	Run: func(cmd *cobra.Command, args []string) {
+		if globalRegistry == nil {
+			fmt.Println("Registry not initialized.")
+			os.Exit(1)
+		}
+
+		concurrency, _ := cmd.Flags().GetInt("concurrency")
+		ctx, cancel := context.WithTimeout(context.Background(), registry.DefaultAskTimeout)
+		run, err := globalRegistry.RunGraph(ctx, registry.RunOptions{Repos: args, Concurrency: concurrency})
+		cancel()
+		if err != nil {
+			fmt.Printf("Error starting run: %v\n", err)
+			os.Exit(1)
+		}
+
+		err = run.Wait(context.Background())
+		displayRun(run)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// Command to show the dependency graph and where each edge came from.
+var depsCmd = &cobra.Command{
+	Use:   "deps [repository]",
+	Short: "Show repository dependencies and their sources",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if globalRegistry == nil {
+			fmt.Println("Registry not initialized.")
+			os.Exit(1)
+		}
+
+		edges := globalRegistry.Coordinator.Edges()
+		repos := make([]string, 0, len(edges))
+		for repo := range edges {
+			if len(args) == 0 || args[0] == repo {
+				repos = append(repos, repo)
+			}
+		}
+		sort.Strings(repos)
+		if len(repos) == 0 {
+			fmt.Println("No dependencies recorded.")
+			return
+		}
+		for _, repo := range repos {
+			for _, edge := range edges[repo] {
+				fmt.Printf(" - %s\n", edge)
+			}
+		}
+	},
+}
+
+// Command group running Git operations across registered repositories.
+var gitCmd = &cobra.Command{
+	Use:   "git",
+	Short: "Run Git status, fetch or pull across repositories",
+}
+
+// newGitCmd creates the git subcommand running op.
+func newGitCmd(op registry.GitOperation, short string) *cobra.Command {
+	return &cobra.Command{
+		Use:   string(op),
+		Short: short,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if globalRegistry == nil {
+				fmt.Println("Registry not initialized.")
+				os.Exit(1)
+			}
+
+			group, _ := cmd.Flags().GetString("group")
+			concurrency, _ := cmd.Flags().GetInt("concurrency")
+			results, err := globalRegistry.Git(context.Background(), op, registry.GitOptions{Group: group, Concurrency: concurrency})
+			if err != nil {
+				fmt.Printf("Error running git %s: %v\n", op, err)
+				os.Exit(1)
+			}
+			if displayGitResults(results) {
+				os.Exit(1)
+			}
+		},
+	}
+}
+
+// Command to score repositories against the health checklist.
+var healthCmd = &cobra.Command{
+	Use:   "health",
+	Short: "Score repositories against a health checklist",
+	Run: func(cmd *cobra.Command, args []string) {
+		if globalRegistry == nil {
+			fmt.Println("Registry not initialized.")
+			os.Exit(1)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), registry.DefaultAskTimeout)
+		defer cancel()
+		report := globalRegistry.Health(ctx)
+
+		if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(report); err != nil {
+				fmt.Printf("Error encoding health report: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+		displayHealth(report)
+	},
+}
+
+// Command group managing the registry's git hooks in repositories.
+var hooksCmd = &cobra.Command{
+	Use:   "hooks",
+	Short: "Install, update or remove managed git hooks",
+}
+
+// newHooksCmd creates the hooks subcommand applying action.
+func newHooksCmd(action registry.HookAction, short string) *cobra.Command {
+	return &cobra.Command{
+		Use:   string(action) + " [repository...]",
+		Short: short,
+		Run: func(cmd *cobra.Command, args []string) {
+			if globalRegistry == nil {
+				fmt.Println("Registry not initialized.")
+				os.Exit(1)
+			}
+
+			group, _ := cmd.Flags().GetString("group")
+			force, _ := cmd.Flags().GetBool("force")
+			ctx, cancel := context.WithTimeout(context.Background(), registry.DefaultAskTimeout)
+			defer cancel()
+			results, err := globalRegistry.Hooks(ctx, action, registry.HookOptions{Repos: args, Group: group, Force: force})
+			if err != nil {
+				fmt.Printf("Error running hooks %s: %v\n", action, err)
+				os.Exit(1)
+			}
+
+			failed := false
+			for _, result := range results {
+				if result.Err != nil {
+					failed = true
+					fmt.Printf(" - %-20s error: %v\n", result.Repo, result.Err)
+					continue
+				}
+				fmt.Printf(" - %-20s %s\n", result.Repo, describeHooks(result.Hooks))
+			}
+			if failed {
+				os.Exit(1)
+			}
+		},
+	}
+}
+
+// Command to show commits from every repository in one timeline.
+var logCmd = &cobra.Command{
+	Use:   "log [repository...]",
+	Short: "Show a merged commit timeline across repositories",
+	Run: func(cmd *cobra.Command, args []string) {
+		if globalRegistry == nil {
+			fmt.Println("Registry not initialized.")
+			os.Exit(1)
+		}
+
+		sinceFlag, _ := cmd.Flags().GetString("since")
+		author, _ := cmd.Flags().GetString("author")
+		limit, _ := cmd.Flags().GetInt("limit")
+		since, err := registry.ParseSince(sinceFlag, time.Now())
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), logTimeout)
+		defer cancel()
+		entries, errs := globalRegistry.Log(ctx, registry.LogOptions{Since: since, Author: author, Repos: args, Limit: limit})
+		for _, entry := range entries {
+			fmt.Printf("%s  %-20s %s  %-16s %s\n",
+				entry.When.Format("2006-01-02 15:04"),
+				entry.Repo,
+				entry.ShortHash(),
+				entry.Author,
+				entry.Subject,
+			)
+		}
+		if len(entries) == 0 {
+			fmt.Println("No commits found.")
+		}
+		failed := make([]string, 0, len(errs))
+		for repo, err := range errs {
+			// Registered directories that are not Git repositories have no
+			// history to show unless asked for by name.
+			if len(args) == 0 && errors.Is(err, registry.ErrNotGitRepo) {
+				continue
+			}
+			failed = append(failed, repo)
+		}
+		sort.Strings(failed)
+		for _, repo := range failed {
+			fmt.Printf("Error reading '%s': %v\n", repo, errs[repo])
+		}
+		if len(failed) > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+// Command group for feature branches spanning several repositories.
+var branchCmd = &cobra.Command{
+	Use:   "branch",
+	Short: "Create, switch or delete a branch across repositories",
+}
+
+// newBranchCmd creates the branch subcommand applying action.
+func newBranchCmd(action registry.BranchAction, short string) *cobra.Command {
+	return &cobra.Command{
+		Use:   string(action) + " [branch]",
+		Short: short,
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if globalRegistry == nil {
+				fmt.Println("Registry not initialized.")
+				os.Exit(1)
+			}
+
+			repos, _ := cmd.Flags().GetStringSlice("repos")
+			group, _ := cmd.Flags().GetString("group")
+			ctx, cancel := context.WithTimeout(context.Background(), registry.DefaultAskTimeout)
+			defer cancel()
+			changes, err := globalRegistry.Branch(ctx, action, args[0], registry.BranchOptions{Repos: repos, Group: group})
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			for _, change := range changes {
+				fmt.Printf(" - %-20s %s %s\n", change.Repo, change.Action, change.Branch)
+			}
+		},
+	}
+}
+
+// Command showing which repositories have a branch checked out.
+var branchStatusCmd = &cobra.Command{
+	Use:   "status [branch]",
+	Short: "Show which repositories have a branch checked out",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if globalRegistry == nil {
+			fmt.Println("Registry not initialized.")
+			os.Exit(1)
+		}
+
+		repos, _ := cmd.Flags().GetStringSlice("repos")
+		group, _ := cmd.Flags().GetString("group")
+		ctx, cancel := context.WithTimeout(context.Background(), registry.DefaultAskTimeout)
+		defer cancel()
+		for _, view := range globalRegistry.Branches(ctx, args[0], registry.BranchOptions{Repos: repos, Group: group}) {
+			switch {
+			case view.Err != nil:
+				fmt.Printf(" - %-20s error: %v\n", view.Repo, view.Err)
+			case view.CheckedOut:
+				fmt.Printf(" * %-20s %s\n", view.Repo, view.Current)
+			case view.Exists:
+				fmt.Printf(" - %-20s %s (has %s)\n", view.Repo, view.Current, args[0])
+			default:
+				fmt.Printf(" - %-20s %s\n", view.Repo, view.Current)
+			}
+		}
 	},
 }
 
 func init() {
+	for _, cmd := range []*cobra.Command{
+		newBranchCmd(registry.BranchCreate, "Create a branch at HEAD and check it out"),
+		newBranchCmd(registry.BranchSwitch, "Check out an existing branch"),
+		newBranchCmd(registry.BranchDelete, "Delete a branch"),
+		branchStatusCmd,
+	} {
+		cmd.Flags().StringSlice("repos", nil, "Repositories to include (default all)")
+		cmd.Flags().String("group", "", "Only include repositories in this manifest group")
+		branchCmd.AddCommand(cmd)
+	}
+	logCmd.Flags().String("since", "2w", "Only show commits newer than a date (2006-01-02) or an age such as 2w, 3d or 12h")
+	logCmd.Flags().String("author", "", "Only show commits whose author name or email contains this")
+	logCmd.Flags().Int("limit", 0, "Maximum number of commits to show")
+	for _, cmd := range []*cobra.Command{
+		newHooksCmd(registry.HookInstall, "Install missing hooks and update outdated ones"),
+		newHooksCmd(registry.HookUpdate, "Update outdated hooks"),
+		newHooksCmd(registry.HookRemove, "Remove managed hooks"),
+	} {
+		cmd.Flags().String("group", "", "Only include repositories in this manifest group")
+		cmd.Flags().Bool("force", false, "Also replace or remove hooks that were modified or not installed by the registry")
+		hooksCmd.AddCommand(cmd)
+	}
+	healthCmd.Flags().Bool("json", false, "Print the report as JSON")
+	runCmd.Flags().Int("concurrency", registry.DefaultConcurrency, "Number of repositories to process in parallel")
+	buildCmd.Flags().String("profile", "", "Build profile from the repository manifest")
+	pushCmd.Flags().Bool("all", false, "Push the tags of every image the registry built, not only the newest")
+	pushCmd.Flags().String("target", "", "Container registry to push to, such as localhost:5000 or ghcr.io/team")
+	pushCmd.Flags().Int("retries", registry.DefaultPushPolicy().Attempts-1, "Times to retry a failed push")
+	watchCmd.Flags().Duration("debounce", registry.DefaultWatchDebounce, "How long a burst of changes must settle before the registry is updated")
+	scanCmd.Flags().Int("depth", registry.DefaultDiscoveryDepth, "Directory levels below the projects path to search (0 for no limit)")
+	scanCmd.Flags().StringSlice("include", nil, "Only register repositories whose path or name matches one of these globs")
+	scanCmd.Flags().StringSlice("exclude", []string{"node_modules"}, "Skip directories whose path or name matches one of these globs")
+	scanCmd.Flags().Bool("nested", false, "Look for nested repositories inside the repositories found")
+	addCmd.Flags().String("branch", "", "Branch to check out when cloning")
+	addCmd.Flags().Int("depth", 0, "Create a shallow clone with this many commits")
+
+	for _, cmd := range []*cobra.Command{
+		newGitCmd(registry.GitStatusOp, "Show the Git status of repositories"),
+		newGitCmd(registry.GitFetchOp, "Fetch the upstream of repositories"),
+		newGitCmd(registry.GitPullOp, "Fast-forward clean repositories to their upstream"),
+	} {
+		cmd.Flags().String("group", "", "Only include repositories in this manifest group")
+		cmd.Flags().Int("concurrency", registry.DefaultConcurrency, "Number of repositories to process in parallel")
+		gitCmd.AddCommand(cmd)
+	}
+
 	rootCmd.AddCommand(listCmd)
 	rootCmd.AddCommand(scanCmd)
 	rootCmd.AddCommand(infoCmd)
 	rootCmd.AddCommand(interactiveCmd)
 	rootCmd.AddCommand(toggleCmd)
+	rootCmd.AddCommand(addCmd)
 	rootCmd.AddCommand(configureCmd)
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(runCmd)
+	rootCmd.AddCommand(depsCmd)
+	rootCmd.AddCommand(gitCmd)
+	rootCmd.AddCommand(healthCmd)
+	rootCmd.AddCommand(hooksCmd)
+	rootCmd.AddCommand(logCmd)
+	rootCmd.AddCommand(branchCmd)
+	rootCmd.AddCommand(watchCmd)
+	rootCmd.AddCommand(buildCmd)
+	rootCmd.AddCommand(imagesCmd)
+	rootCmd.AddCommand(pushCmd)
 }
 
 func main() {
 	var err error
-	globalRegistry, err = registry.NewRegistry()
+	// REGISTRY_METRICS_ADDR (for example ":9090") serves Prometheus metrics.
+	// REGISTRY_PUSH_TARGET and its credentials configure `push`.
+	globalRegistry, err = registry.NewRegistry(
+		registry.WithMetricsAddr(os.Getenv("REGISTRY_METRICS_ADDR")),
+		registry.WithPushTarget(os.Getenv("REGISTRY_PUSH_TARGET")),
+		registry.WithPushCredentials(os.Getenv("REGISTRY_PUSH_USERNAME"), os.Getenv("REGISTRY_PUSH_PASSWORD")),
+	)
 	if err != nil {
 		fmt.Printf("Error initializing registry: %v\n", err)
 		os.Exit(1)
@@ -137,39 +763,230 @@ func main() {
 		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 		<-sigCh
 		fmt.Println("\nShutting down gracefully...")
-		close(globalRegistry.Actor.MsgChan)
-		globalRegistry.actorWg.Wait()
+		if err := shutdownRegistry(); err != nil {
+			fmt.Printf("Error shutting down: %v\n", err)
+			os.Exit(1)
+		}
 		os.Exit(0)
 	}()
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
+		shutdownRegistry()
+		os.Exit(1)
+	}
+	if err := shutdownRegistry(); err != nil {
+		fmt.Printf("Error shutting down: %v\n", err)
 		os.Exit(1)
 	}
 }
 
+// shutdownRegistry stops the registry, giving in-flight work a bounded time
+// to finish.
+func shutdownRegistry() error {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return globalRegistry.Shutdown(ctx)
+}
+
 // displayTable prints the list of registry items in a table format.
 func displayTable(items []registry.RegistryItem) {
 	fmt.Println("Displaying items in table format:")
+	// Children sort right after their parent: by the repository at the top
+	// of their family, then by name, which starts with the parent's.
+	parents := make(map[string]string, len(items))
+	for _, item := range items {
+		parents[item.Name] = item.Parent
+	}
+	family := func(name string) string {
+		for seen := 0; parents[name] != "" && seen < len(items); seen++ {
+			name = parents[name]
+		}
+		return name
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if a, b := family(items[i].Name), family(items[j].Name); a != b {
+			return a < b
+		}
+		return items[i].Name < items[j].Name
+	})
 	for _, item := range items {
 		status := "Disabled"
 		if item.Enabled {
 			status = "Enabled"
 		}
-		fmt.Printf(" - %s: %s [%s]\n", item.Name, item.Path, status)
+		git := "-"
+		if item.GitStatus != nil {
+			git = item.GitStatus.Summary()
+		}
+		if item.Parent != "" {
+			fmt.Printf("     %s (%s): %s [%s] %s\n", item.Name, item.Type, item.Path, status, git)
+			continue
+		}
+		fmt.Printf(" - %s: %s [%s] %s\n", item.Name, item.Path, status, git)
+	}
+}
+
+// displayHistory prints journal entries, oldest first.
+func displayHistory(entries []registry.JournalEntry) {
+	if len(entries) == 0 {
+		fmt.Println("No history recorded.")
+		return
+	}
+	for _, entry := range entries {
+		fmt.Printf("%6d  %s  %-20s %s\n",
+			entry.Seq,
+			entry.Time.Format("2006-01-02 15:04:05"),
+			entry.Repo,
+			entry.Describe(),
+		)
+	}
+}
+
+// displayRun prints the outcome of every repository in a run.
+func displayRun(run *registry.Run) {
+	fmt.Printf("Run %s:\n", run.ID)
+	for _, result := range run.Results() {
+		line := fmt.Sprintf(" - %-20s %-10s %s", result.Name, result.Status, result.Finished.Sub(result.Started).Round(time.Millisecond))
+		if result.Status == registry.NodeSkipped {
+			line = fmt.Sprintf(" - %-20s %-10s", result.Name, result.Status)
+		}
+		if result.Err != nil {
+			line += fmt.Sprintf(" (%v)", result.Err)
+		}
+		fmt.Println(line)
 	}
 }
 
+// displayGitResults prints one line per repository and a count per outcome.
+// It reports whether any repository failed.
+func displayGitResults(results []registry.GitResult) bool {
+	if len(results) == 0 {
+		fmt.Println("No repositories matched.")
+		return false
+	}
+	counts := make(map[registry.GitOutcome]int)
+	for _, result := range results {
+		counts[result.Outcome]++
+		line := fmt.Sprintf(" - %-20s %-14s", result.Repo, result.Outcome)
+		if result.Status.Head != "" {
+			line += " " + result.Status.Summary()
+		}
+		if result.Err != nil {
+			line += fmt.Sprintf(" (%v)", result.Err)
+		}
+		fmt.Println(line)
+	}
+
+	outcomes := make([]string, 0, len(counts))
+	for outcome, count := range counts {
+		outcomes = append(outcomes, fmt.Sprintf("%d %s", count, outcome))
+	}
+	sort.Strings(outcomes)
+	fmt.Printf("%d repositories: %s\n", len(results), strings.Join(outcomes, ", "))
+	return counts[registry.OutcomeFailed] > 0
+}
+
+// displayHealth prints a checklist row per repository and the pass rate of
+// every check.
+func displayHealth(report registry.HealthReport) {
+	if len(report.Repos) == 0 {
+		fmt.Println("No repositories registered.")
+		return
+	}
+	fmt.Printf("%-20s %5s", "REPOSITORY", "SCORE")
+	for _, check := range registry.HealthChecks {
+		fmt.Printf(" %-*s", len(check), check)
+	}
+	fmt.Println()
+	for _, repo := range report.Repos {
+		fmt.Printf("%-20s %4d%%", repo.Repo, repo.Score)
+		if repo.Error != "" {
+			fmt.Printf(" error: %s\n", repo.Error)
+			continue
+		}
+		for _, check := range repo.Checks {
+			mark := "✗"
+			if check.Passed {
+				mark = "✓"
+			}
+			fmt.Printf(" %-*s", len(check.Name), mark)
+		}
+		fmt.Println()
+	}
+
+	fmt.Printf("\nOverall score: %d%%\n", report.Score)
+	for _, check := range registry.HealthChecks {
+		fmt.Printf("  %-18s %d/%d repositories\n", check, report.Passing[check], len(report.Repos))
+	}
+}
+
+// describeHooks summarizes hook states, e.g. "pre-commit v1 (updated)".
+func describeHooks(hooks []registry.HookStatus) string {
+	parts := make([]string, 0, len(hooks))
+	for _, hook := range hooks {
+		part := fmt.Sprintf("%s %s", hook.Name, hook.State)
+		if hook.Version > 0 {
+			part = fmt.Sprintf("%s v%d %s", hook.Name, hook.Version, hook.State)
+		}
+		switch {
+		case hook.Changed:
+			part += " (changed)"
+		case hook.Skipped:
+			part += " (skipped)"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ", ")
+}
+
 // displayRepoInfo prints detailed information about a specific repository.
 func displayRepoInfo(item registry.RegistryItem) {
 	fmt.Printf("Repository Information:\n")
 	fmt.Printf("  Name:          %s\n", item.Name)
 	fmt.Printf("  Path:          %s\n", item.Path)
+	if item.URL != "" {
+		fmt.Printf("  Remote:        %s\n", item.URL)
+	}
 	fmt.Printf("  Type:          %s\n", item.Type)
+	if item.Parent != "" {
+		fmt.Printf("  Parent:        %s\n", item.Parent)
+	}
 	fmt.Printf("  Status:        %s\n", item.Status)
 	fmt.Printf("  Created:       %s\n", item.CreatedAt.Format("2006-01-02 15:04:05"))
 	fmt.Printf("  Last Updated:  %s\n", item.LastUpdated.Format("2006-01-02 15:04:05"))
 	fmt.Printf("  Has Dockerfile: %v\n", item.HasDockerfile)
+	if len(item.Hooks) > 0 {
+		fmt.Printf("  Hooks:         %s\n", describeHooks(item.Hooks))
+	}
+
+	switch {
+	case item.ManifestErr != nil:
+		fmt.Printf("  Manifest:      %v\n", item.ManifestErr)
+	case item.Manifest != nil:
+		fmt.Printf("  Depends On:    %s\n", strings.Join(item.Manifest.DependsOn, ", "))
+		fmt.Printf("  Groups:        %s\n", strings.Join(item.Manifest.Groups, ", "))
+		for key, value := range item.Manifest.Labels {
+			fmt.Printf("  Label:         %s=%s\n", key, value)
+		}
+	}
+
+	if item.GitStatus != nil {
+		status := item.GitStatus
+		fmt.Printf("  Git Status:    %s\n", status.Summary())
+		fmt.Printf("  HEAD:          %s\n", status.Head)
+		fmt.Printf("  Changes:       %d modified, %d untracked\n", status.Modified, status.Untracked)
+		if status.Upstream != "" && status.AheadBehindUnknown {
+			fmt.Printf("  Upstream:      %s (too far apart to count)\n", status.Upstream)
+		} else if status.Upstream != "" {
+			fmt.Printf("  Upstream:      %s (%d ahead, %d behind)\n", status.Upstream, status.Ahead, status.Behind)
+		}
+		if !status.LastCommit.IsZero() {
+			fmt.Printf("  Last Commit:   %s\n", status.LastCommit.Format("2006-01-02 15:04:05"))
+		}
+	} else if item.GitErr != nil {
+		fmt.Printf("  Git Status:    %v\n", item.GitErr)
+	}
 
 	if item.GitRepo != nil {
 		head, err := item.GitRepo.Head()
@@ -2,11 +2,15 @@
 package registry
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"sync"
 	"time"
+
+	git "github.com/go-git/go-git/v5"
 )
 
 // Message is the interface for all messages
@@ -14,8 +18,12 @@ type Message interface{}
 
 // Commands for RegistryActor
 type AddRepo struct {
-	Name string
-	Path string
+	Name   string
+	Path   string
+	URL    string // Cloned into Path when the repository initializes
+	Clone  CloneOptions
+	Kind   RepoKind // KindRepository when empty
+	Parent string   // Repository this one is a child of, if any
 }
 
 type RemoveRepo struct {
@@ -24,6 +32,7 @@ type RemoveRepo struct {
 
 type ScanDir struct {
 	Directory string
+	Options   DiscoveryOptions
 }
 
 type ToggleRepo struct {
@@ -38,6 +47,14 @@ type ReportCompletion struct {
 	Name string
 }
 
+// Sync is answered once every message queued before it has been handled.
+type Sync struct{}
+
+// RefreshStatus re-reads a repository's Git status. The reply is a GitStatus.
+type RefreshStatus struct {
+	Name string
+}
+
 type ConfigureDocker struct{}
 type ConfigurePipeline struct{}
 type InitRepo struct{}
@@ -46,105 +63,475 @@ type InitRepo struct{}
 type RepoActor struct {
 	Name        string
 	Path        string
+	URL         string // Remote the repository was cloned from, if any
+	Kind        RepoKind
+	Parent      string // Repository this one is a child of, if any
 	Active      bool
 	IsDocker    bool
 	HasPipeline bool
-	MsgChan     chan Message
+	CreatedAt   time.Time
+	LastUpdated time.Time
+	Metadata    map[string]string
+	Manifest    *Manifest
+	Mailbox     *Mailbox
 	wg          *sync.WaitGroup
+	supervisor  *Supervisor
+	journal     *Journal
+	status      string
+	initErr     error
+	cloneOpts   CloneOptions
+	manifestErr error
+	enabled     *bool // Manifest enabled value Active was last set from
+	gitRepo     *git.Repository
+	gitStatus   *GitStatus
+	gitErr      error
+	history     *commitHistory
+	ctx         context.Context // Cancelled when the registry shuts down
+	done        <-chan struct{}
+	metrics     *Metrics
+	mu          sync.RWMutex
 }
 
 // NewRepoActor initializes a new RepoActor
 func NewRepoActor(name, path string, wg *sync.WaitGroup) *RepoActor {
+	now := time.Now()
 	return &RepoActor{
-		Name:    name,
-		Path:    path,
-		Active:  true,
-		MsgChan: make(chan Message),
-		wg:      wg,
+		Name:        name,
+		Path:        path,
+		Active:      true,
+		CreatedAt:   now,
+		LastUpdated: now,
+		Kind:        KindRepository,
+		Metadata:    make(map[string]string),
+		Mailbox:     NewMailbox(DefaultMailboxCapacity, Block),
+		wg:          wg,
+		status:      "active",
+		ctx:         context.Background(),
 	}
 }
 
-// Start launches the RepoActor's goroutine
+// newRepoActorFromState recreates a RepoActor from its persisted state.
+func newRepoActorFromState(state RepoState, wg *sync.WaitGroup) *RepoActor {
+	repo := NewRepoActor(state.Name, state.Path, wg)
+	repo.URL = state.URL
+	repo.cloneOpts.Branch = state.Branch
+	repo.Parent = state.Parent
+	if state.Kind != "" {
+		repo.Kind = state.Kind
+	}
+	repo.Active = state.Active
+	repo.IsDocker = state.IsDocker
+	repo.HasPipeline = state.HasPipeline
+	if !state.CreatedAt.IsZero() {
+		repo.CreatedAt = state.CreatedAt
+	}
+	if !state.LastUpdated.IsZero() {
+		repo.LastUpdated = state.LastUpdated
+	}
+	for k, v := range state.Metadata {
+		repo.Metadata[k] = v
+	}
+	// The restored active state wins over the manifest until its enabled
+	// value changes.
+	if manifest, _ := repo.loadManifest(); manifest != nil {
+		repo.enabled = manifest.Enabled
+	}
+	repo.openGit()
+	return repo
+}
+
+// detect sets IsDocker and HasPipeline from what exists on disk, and the
+// active state from the manifest when its enabled value is new, so a toggle
+// is not undone by unrelated edits.
+func (r *RepoActor) detect() {
+	_, err := os.Stat(filepath.Join(r.Path, "Dockerfile"))
+	_, pipelineErr := os.Stat(filepath.Join(r.Path, ".github", "workflows"))
+	r.openGit()
+	manifest, _ := r.loadManifest()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.IsDocker = err == nil
+	r.HasPipeline = pipelineErr == nil
+	if manifest != nil && manifest.Enabled != nil && (r.enabled == nil || *r.enabled != *manifest.Enabled) {
+		r.Active = *manifest.Enabled
+		r.enabled = manifest.Enabled
+	}
+}
+
+// loadManifest re-reads the repository manifest. An invalid manifest is
+// kept as an error and leaves the repository without one.
+func (r *RepoActor) loadManifest() (*Manifest, error) {
+	manifest, err := LoadManifest(r.Path)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Manifest = manifest
+	r.manifestErr = err
+	return manifest, err
+}
+
+// state returns the persisted form of the actor.
+func (r *RepoActor) state() RepoState {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	metadata := make(map[string]string, len(r.Metadata))
+	for k, v := range r.Metadata {
+		metadata[k] = v
+	}
+	return RepoState{
+		Name:        r.Name,
+		Path:        r.Path,
+		URL:         r.URL,
+		Branch:      r.cloneOpts.Branch,
+		Kind:        r.Kind,
+		Parent:      r.Parent,
+		Active:      r.Active,
+		IsDocker:    r.IsDocker,
+		HasPipeline: r.HasPipeline,
+		CreatedAt:   r.CreatedAt,
+		LastUpdated: r.LastUpdated,
+		Metadata:    metadata,
+	}
+}
+
+// Start launches the RepoActor's goroutine under its supervisor
 func (r *RepoActor) Start() {
-	r.wg.Add(1)
-	go func() {
-		defer r.wg.Done()
-		for msg := range r.MsgChan {
-			switch m := msg.(type) {
-			case ToggleRepo:
-				r.Active = !r.Active
-				fmt.Printf("Repo '%s' toggled to %v\n", r.Name, r.Active)
-			case ConfigureDocker:
-				if r.Active && !r.IsDocker {
-					r.addDockerfile()
-					r.IsDocker = true
-					fmt.Printf("Docker configured for repo '%s'\n", r.Name)
-				}
-			case ConfigurePipeline:
-				if r.Active && !r.HasPipeline {
-					r.setupPipeline()
-					r.HasPipeline = true
-					fmt.Printf("Pipeline configured for repo '%s'\n", r.Name)
-				}
-			case InitRepo:
-				if r.Active {
-					r.initializeRepo()
-				}
-			case ReportCompletion:
-				fmt.Printf("Repo '%s' has completed its task.\n", m.Name)
-			default:
-				fmt.Printf("Repo '%s' received unknown message: %v\n", r.Name, msg)
-			}
+	if r.supervisor == nil {
+		r.supervisor = NewSupervisor(DefaultRestartPolicy(), nil, r.wg)
+	}
+	r.done = r.supervisor.Supervise(ChildSpec{
+		Name:     r.Name,
+		Run:      r.run,
+		OnState:  r.setState,
+		OnFailed: r.drain,
+	})
+}
+
+// run processes messages until the mailbox is closed.
+func (r *RepoActor) run() {
+	for {
+		msg, ok := r.Mailbox.Receive()
+		if !ok {
+			return
+		}
+		r.handle(msg)
+	}
+}
+
+// handle answers a single message. If the handler panics the sender is told
+// before the panic is passed on to the supervisor.
+func (r *RepoActor) handle(msg Message) {
+	ctx, m, reply := unwrap(msg)
+	ctx, cancel := withShutdown(ctx, r.ctx)
+	defer cancel()
+	if err := ctx.Err(); err != nil {
+		reply(nil, err)
+		return
+	}
+	start := time.Now()
+	defer func() {
+		if p := recover(); p != nil {
+			err := fmt.Errorf("repo '%s' crashed handling %T: %v", r.Name, m, p)
+			r.metrics.ObserveMessage(r.actorName(), m, start, err)
+			reply(nil, err)
+			panic(p)
 		}
 	}()
+	value, err := r.receive(ctx, m)
+	r.metrics.ObserveMessage(r.actorName(), m, start, err)
+	reply(value, err)
+}
+
+// actorName identifies the actor in metrics.
+func (r *RepoActor) actorName() string {
+	return "repo/" + r.Name
+}
+
+// drain answers every remaining message with ErrActorFailed.
+func (r *RepoActor) drain(cause error) {
+	for {
+		msg, ok := r.Mailbox.Receive()
+		if !ok {
+			return
+		}
+		_, _, reply := unwrap(msg)
+		reply(nil, fmt.Errorf("%w: %v", ErrActorFailed, cause))
+	}
+}
+
+// setState records the supervisor's view of the actor in its status.
+func (r *RepoActor) setState(state ChildState, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch state {
+	case ChildRunning:
+		r.status = fmt.Sprintf("active (restarted after: %v)", err)
+	case ChildRestarting:
+		r.status = fmt.Sprintf("restarting: %v", err)
+	case ChildFailed:
+		r.status = fmt.Sprintf("failed: %v", err)
+	}
+}
+
+// receive handles a single message and returns the result for the sender.
+func (r *RepoActor) receive(ctx context.Context, msg Message) (interface{}, error) {
+	switch m := msg.(type) {
+	case ToggleRepo:
+		r.mu.Lock()
+		r.Active = !r.Active
+		r.LastUpdated = time.Now()
+		r.mu.Unlock()
+		active := r.Active
+		r.record(JournalEntry{Type: JournalToggleRepo, Active: &active})
+		fmt.Printf("Repo '%s' toggled to %v\n", r.Name, r.Active)
+		return ToggleResult{Name: r.Name, Active: r.Active}, nil
+	case ConfigureDocker:
+		if !r.Active {
+			return nil, fmt.Errorf("%w: %s", ErrRepoInactive, r.Name)
+		}
+		if !r.IsDocker {
+			if err := r.addDockerfile(); err != nil {
+				return nil, err
+			}
+			r.mu.Lock()
+			r.IsDocker = true
+			r.LastUpdated = time.Now()
+			r.mu.Unlock()
+			r.record(JournalEntry{Type: JournalConfigureDocker})
+			fmt.Printf("Docker configured for repo '%s'\n", r.Name)
+		}
+		return r.IsDocker, nil
+	case ConfigurePipeline:
+		if !r.Active {
+			return nil, fmt.Errorf("%w: %s", ErrRepoInactive, r.Name)
+		}
+		if !r.HasPipeline {
+			if err := r.setupPipeline(); err != nil {
+				return nil, err
+			}
+			r.mu.Lock()
+			r.HasPipeline = true
+			r.LastUpdated = time.Now()
+			r.mu.Unlock()
+			r.record(JournalEntry{Type: JournalConfigurePipeline})
+			fmt.Printf("Pipeline configured for repo '%s'\n", r.Name)
+		}
+		return r.HasPipeline, nil
+	case InitRepo:
+		if r.Active {
+			if err := r.initializeRepo(ctx); err != nil {
+				r.mu.Lock()
+				r.initErr = err
+				r.status = fmt.Sprintf("failed: %v", err)
+				r.mu.Unlock()
+				fmt.Printf("Error initializing repository '%s': %v\n", r.Name, err)
+				return nil, err
+			}
+		}
+		// A status error is kept with the repository, initialization succeeded.
+		r.refreshStatus()
+		return nil, nil
+	case RefreshStatus:
+		return r.refreshStatus()
+	case DetectFeatures:
+		return r.detectFeatures(), nil
+	case ChangeBranch:
+		return r.changeBranch(m.Action, m.Name)
+	case RevertBranch:
+		return nil, r.revertBranch(m.Change)
+	case ReadBranch:
+		return r.readBranch(m.Name)
+	case ReadLog:
+		return r.readLog(m)
+	case ReadImageMetadata:
+		return r.readImageMetadata()
+	case ManageHooks:
+		return r.manageHooks(m.Action, m.Force)
+	case CheckHealth:
+		return r.checkHealth(time.Now()), nil
+	case RunGit:
+		result := r.runGit(ctx, m.Operation)
+		return result, result.Err
+	case ReportCompletion:
+		fmt.Printf("Repo '%s' has completed its task.\n", m.Name)
+		return nil, nil
+	case Sync:
+		return nil, nil
+	default:
+		fmt.Printf("Repo '%s' received unknown message: %v\n", r.Name, msg)
+		return nil, fmt.Errorf("repo '%s' received unknown message %T", r.Name, msg)
+	}
+}
+
+// record appends a journal entry for this repository.
+func (r *RepoActor) record(entry JournalEntry) {
+	entry.Repo = r.Name
+	if err := r.journal.Append(entry); err != nil {
+		fmt.Printf("Error journaling %s for '%s': %v\n", entry.Type, r.Name, err)
+	}
+}
+
+// openGit opens the repository with go-git. Paths that are not (yet) Git
+// repositories leave it nil.
+func (r *RepoActor) openGit() {
+	repo, err := openRepository(r.Path)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.gitRepo = nil
+		r.gitErr = fmt.Errorf("%w: %s", ErrNotGitRepo, r.Path)
+		return
+	}
+	r.gitRepo = repo
+	r.gitErr = nil
+}
+
+// refreshStatus re-reads and caches the Git status.
+func (r *RepoActor) refreshStatus() (GitStatus, error) {
+	r.mu.RLock()
+	repo := r.gitRepo
+	r.mu.RUnlock()
+	if repo == nil {
+		// The repository may have been created since the actor started.
+		r.openGit()
+		r.mu.RLock()
+		repo = r.gitRepo
+		err := r.gitErr
+		r.mu.RUnlock()
+		if repo == nil {
+			return GitStatus{}, err
+		}
+	}
+
+	status, err := ReadGitStatus(repo)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.gitErr = fmt.Errorf("failed to read git status of '%s': %w", r.Name, err)
+		return status, r.gitErr
+	}
+	r.gitStatus = &status
+	r.gitErr = nil
+	return status, nil
 }
 
 // Helper methods for RepoActor
-func (r *RepoActor) addDockerfile() {
+func (r *RepoActor) addDockerfile() error {
 	dockerfilePath := filepath.Join(r.Path, "Dockerfile")
 	content := "FROM alpine:latest\nCMD [\"echo\", \"Hello, Docker!\"]\n"
-	err := os.WriteFile(dockerfilePath, []byte(content), 0644)
-	if err != nil {
-		fmt.Printf("Error adding Dockerfile to '%s': %v\n", r.Name, err)
+	if err := os.WriteFile(dockerfilePath, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to add Dockerfile to '%s': %w", r.Name, err)
 	}
+	return nil
 }
 
-func (r *RepoActor) setupPipeline() {
+func (r *RepoActor) setupPipeline() error {
 	pipelinePath := filepath.Join(r.Path, ".github", "workflows", "pipeline.yml")
 	content := "name: CI\non: [push]\njobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n      - uses: actions/checkout@v2\n"
-	err := os.MkdirAll(filepath.Dir(pipelinePath), os.ModePerm)
-	if err != nil {
-		fmt.Printf("Error creating pipeline directory for '%s': %v\n", r.Name, err)
-		return
+	if err := os.MkdirAll(filepath.Dir(pipelinePath), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create pipeline directory for '%s': %w", r.Name, err)
 	}
-	err = os.WriteFile(pipelinePath, []byte(content), 0644)
-	if err != nil {
-		fmt.Printf("Error setting up pipeline for '%s': %v\n", r.Name, err)
+	if err := os.WriteFile(pipelinePath, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to set up pipeline for '%s': %w", r.Name, err)
 	}
+	return nil
 }
 
-func (r *RepoActor) initializeRepo() {
-	// Simulate repository initialization (e.g., cloning, setting up)
+// initializeRepo clones the repository when it was added by URL and its path
+// is not a Git repository yet.
+func (r *RepoActor) initializeRepo(ctx context.Context) error {
 	fmt.Printf("Initializing repository '%s'...\n", r.Name)
-	time.Sleep(1 * time.Second) // Simulate time-consuming task
+	r.mu.RLock()
+	cloned := r.gitRepo != nil
+	r.mu.RUnlock()
+	if r.URL != "" && !cloned {
+		if err := r.clone(ctx); err != nil {
+			return err
+		}
+	}
 	fmt.Printf("Repository '%s' initialized.\n", r.Name)
+	return nil
 }
 
 // RegistryActor manages all repositories
 type RegistryActor struct {
 	Repos      map[string]*RepoActor
-	MsgChan    chan Message
+	Mailbox    *Mailbox
+	Events     *EventBus
 	wg         *sync.WaitGroup
+	supervisor *Supervisor
+	journal    *Journal
+	onChange   func()
+	capacity   int
+	overflow   OverflowPolicy
+	ctx        context.Context // Cancelled when the registry shuts down
+	done       chan struct{}
+	metrics    *Metrics
 	mutex      sync.Mutex
 }
 
 // NewRegistryActor initializes a new RegistryActor
 func NewRegistryActor(wg *sync.WaitGroup) *RegistryActor {
+	events := NewEventBus()
 	return &RegistryActor{
-		Repos:   make(map[string]*RepoActor),
-		MsgChan: make(chan Message),
-		wg:      wg,
+		Repos:      make(map[string]*RepoActor),
+		Mailbox:    NewMailbox(DefaultMailboxCapacity, Block),
+		Events:     events,
+		wg:         wg,
+		supervisor: NewSupervisor(DefaultRestartPolicy(), events, wg),
+		capacity:   DefaultMailboxCapacity,
+		overflow:   Block,
+		ctx:        context.Background(),
+		done:       make(chan struct{}),
+	}
+}
+
+// SetMailboxOptions sets the capacity and overflow policy of the registry
+// mailbox and of every RepoActor mailbox created from now on. It must be
+// called before Start.
+func (r *RegistryActor) SetMailboxOptions(capacity int, overflow OverflowPolicy) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.capacity = capacity
+	r.overflow = overflow
+	r.Mailbox = NewMailbox(capacity, overflow)
+}
+
+// SetRestartPolicy changes how crashed RepoActors started from now on are
+// restarted.
+func (r *RegistryActor) SetRestartPolicy(policy RestartPolicy) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.supervisor = NewSupervisor(policy, r.Events, r.wg)
+}
+
+// SetContext sets the context every operation of the registry and its
+// RepoActors runs under. Cancelling it aborts in-flight work. It must be
+// called before Start.
+func (r *RegistryActor) SetContext(ctx context.Context) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.ctx = ctx
+}
+
+// SetMetrics makes the actor and the RepoActors it starts from now on record
+// metrics. It must be called before Start.
+func (r *RegistryActor) SetMetrics(metrics *Metrics) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.metrics = metrics
+}
+
+// Mailboxes returns the registry's mailbox and every RepoActor mailbox,
+// keyed by actor name.
+func (r *RegistryActor) Mailboxes() map[string]*Mailbox {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	mailboxes := map[string]*Mailbox{"registry": r.Mailbox}
+	for _, repo := range r.Repos {
+		mailboxes[repo.actorName()] = repo.Mailbox
 	}
+	return mailboxes
 }
 
 // Start launches the RegistryActor's goroutine
@@ -152,191 +539,401 @@ func (r *RegistryActor) Start() {
 	r.wg.Add(1)
 	go func() {
 		defer r.wg.Done()
-		for msg := range r.MsgChan {
-			switch m := msg.(type) {
-			case AddRepo:
-				r.addRepo(m.Name, m.Path)
-			case RemoveRepo:
-				r.removeRepo(m.Name)
-			case ScanDir:
-				r.scanDirectory(m.Directory)
-			case ToggleRepo:
-				r.toggleRepo(m.Name)
-			case ConfigureRepo:
-				r.configureRepo(m.Name)
-			default:
-				fmt.Printf("Registry received unknown message: %v\n", msg)
+		defer close(r.done)
+		for {
+			msg, ok := r.Mailbox.Receive()
+			if !ok {
+				return
 			}
+			r.handle(msg)
 		}
 	}()
 }
 
-// Add a new repository
-func (r *RegistryActor) addRepo(name, path string) {
+// Done is closed once the actor's mailbox is closed and drained.
+func (r *RegistryActor) Done() <-chan struct{} {
+	return r.done
+}
+
+// handle answers a single message under the registry context.
+func (r *RegistryActor) handle(msg Message) {
+	ctx, m, reply := unwrap(msg)
+	ctx, cancel := withShutdown(ctx, r.ctx)
+	defer cancel()
+	if err := ctx.Err(); err != nil {
+		reply(nil, err)
+		return
+	}
+	start := time.Now()
+	value, err := r.receive(ctx, m)
+	r.metrics.ObserveMessage("registry", m, start, err)
+	reply(value, err)
+}
+
+// CloseRepos stops restarting crashed RepoActors and closes the mailboxes of
+// the named repositories one at a time, waiting for each to drain. Repositories
+// missing from order are closed afterwards. It gives up when ctx is done.
+func (r *RegistryActor) CloseRepos(ctx context.Context, order []string) error {
+	r.mutex.Lock()
+	r.supervisor.Stop()
+	remaining := make(map[string]*RepoActor, len(r.Repos))
+	for name, repo := range r.Repos {
+		remaining[name] = repo
+	}
+	r.mutex.Unlock()
+
+	var repos []*RepoActor
+	for _, name := range order {
+		if repo, exists := remaining[name]; exists {
+			repos = append(repos, repo)
+			delete(remaining, name)
+		}
+	}
+	for _, repo := range remaining {
+		repos = append(repos, repo)
+	}
+
+	for _, repo := range repos {
+		repo.Mailbox.Close()
+		if repo.done == nil {
+			continue
+		}
+		select {
+		case <-repo.done:
+		case <-ctx.Done():
+			return fmt.Errorf("failed to close repository '%s': %w", repo.Name, ctx.Err())
+		}
+	}
+	return nil
+}
+
+// receive handles a single message and returns the result for the sender.
+// State-changing messages are persisted before the sender is answered.
+func (r *RegistryActor) receive(ctx context.Context, msg Message) (result interface{}, err error) {
+	defer func() {
+		if err != nil || !changesState(msg) {
+			return
+		}
+		r.mutex.Lock()
+		onChange := r.onChange
+		r.mutex.Unlock()
+		if onChange != nil {
+			onChange()
+		}
+	}()
+
+	switch m := msg.(type) {
+	case AddRepo:
+		return r.addRepo(m)
+	case RemoveRepo:
+		return nil, r.removeRepo(m.Name)
+	case ScanDir:
+		return r.scanDirectory(m.Directory, m.Options)
+	case ToggleRepo:
+		return r.toggleRepo(ctx, m.Name)
+	case ConfigureRepo:
+		return r.configureRepo(ctx, m.Name)
+	case RefreshStatus:
+		return r.refreshStatus(ctx, m.Name)
+	case Sync:
+		return nil, nil
+	default:
+		fmt.Printf("Registry received unknown message: %v\n", msg)
+		return nil, fmt.Errorf("registry received unknown message %T", msg)
+	}
+}
+
+// changesState reports whether msg alters what the registry persists.
+func changesState(msg Message) bool {
+	switch msg.(type) {
+	case AddRepo, RemoveRepo, ToggleRepo, ConfigureRepo:
+		return true
+	}
+	return false
+}
+
+// OnChange registers fn to run after every state-changing message.
+func (r *RegistryActor) OnChange(fn func()) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
-	if _, exists := r.Repos[name]; exists {
-		fmt.Printf("Repository '%s' already exists.\n", name)
-		return
+	r.onChange = fn
+}
+
+// SetJournal makes the actor record state-changing messages in journal.
+func (r *RegistryActor) SetJournal(journal *Journal) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.journal = journal
+	for _, repo := range r.Repos {
+		repo.journal = journal
 	}
-	repo := NewRepoActor(name, path, r.wg)
-	repo.Start()
-	r.Repos[name] = repo
-	fmt.Printf("Repository '%s' added.\n", name)
-	// Initialize the repo
-	repo.MsgChan <- InitRepo{}
 }
 
-// Remove a repository
-func (r *RegistryActor) removeRepo(name string) {
+// Restore recreates RepoActors from persisted state. Repositories that are
+// already registered are left untouched.
+func (r *RegistryActor) Restore(state *State) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
-	if repo, exists := r.Repos[name]; exists {
-		repo.MsgChan <- ReportCompletion{Name: name}
-		close(repo.MsgChan)
-		delete(r.Repos, name)
-		fmt.Printf("Repository '%s' removed.\n", name)
-	} else {
-		fmt.Printf("Repository '%s' not found.\n", name)
+	for name, repoState := range state.Repos {
+		if _, exists := r.Repos[name]; exists {
+			continue
+		}
+		repoState.Name = name
+		repo := newRepoActorFromState(repoState, r.wg)
+		r.spawn(repo)
+		// The mailbox was just created, so this cannot block.
+		repo.Mailbox.Send(context.Background(), RefreshStatus{Name: name})
 	}
 }
 
-// Toggle a repository's active state
-func (r *RegistryActor) toggleRepo(name string) {
+// spawn wires a RepoActor to the registry's supervisor, journal and mailbox
+// settings, starts it and registers it. The caller must hold r.mutex.
+func (r *RegistryActor) spawn(repo *RepoActor) {
+	repo.supervisor = r.supervisor
+	repo.journal = r.journal
+	repo.ctx = r.ctx
+	repo.metrics = r.metrics
+	repo.Mailbox = NewMailbox(r.capacity, r.overflow)
+	repo.Start()
+	r.Repos[repo.Name] = repo
+}
+
+// States returns the persisted form of every registered repository.
+func (r *RegistryActor) States() map[string]RepoState {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
-	if repo, exists := r.Repos[name]; exists {
-		repo.MsgChan <- ToggleRepo{Name: name}
-	} else {
-		fmt.Printf("Repository '%s' not found for toggling.\n", name)
+	states := make(map[string]RepoState, len(r.Repos))
+	for name, repo := range r.Repos {
+		states[name] = repo.state()
 	}
+	return states
 }
 
-// Configure a repository
-func (r *RegistryActor) configureRepo(name string) {
+// Names returns the names of every registered repository.
+func (r *RegistryActor) Names() []string {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
-	if repo, exists := r.Repos[name]; exists {
-		// Example: Configure Docker and Pipeline
-		repo.MsgChan <- ConfigureDocker{}
-		repo.MsgChan <- ConfigurePipeline{}
-	} else {
-		fmt.Printf("Repository '%s' not found for configuration.\n", name)
+	names := make([]string, 0, len(r.Repos))
+	for name := range r.Repos {
+		names = append(names, name)
 	}
+	return names
 }
 
-// Scan a directory for repositories
-func (r *RegistryActor) scanDirectory(directory string) {
-	fmt.Printf("Scanning directory '%s' for repositories...\n", directory)
-	err := filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
+// ReloadManifests re-reads the manifest of every registered repository.
+// Repositories without a manifest map to nil; invalid ones are reported in
+// the error map instead.
+func (r *RegistryActor) ReloadManifests() (map[string]*Manifest, map[string]error) {
+	r.mutex.Lock()
+	repos := make([]*RepoActor, 0, len(r.Repos))
+	for _, repo := range r.Repos {
+		repos = append(repos, repo)
+	}
+	r.mutex.Unlock()
+
+	manifests := make(map[string]*Manifest, len(repos))
+	errs := make(map[string]error)
+	for _, repo := range repos {
+		manifest, err := repo.loadManifest()
 		if err != nil {
-			return err
-		}
-		if info.IsDir() && info.Name() == ".git" {
-			repoPath := filepath.Dir(path)
-			repoName := filepath.Base(repoPath)
-			r.MsgChan <- AddRepo{Name: repoName, Path: repoPath}
+			errs[repo.Name] = err
+			continue
 		}
-		return nil
-	})
-	if err != nil {
-		fmt.Printf("Error scanning directory: %v\n", err)
+		manifests[repo.Name] = manifest
 	}
+	return manifests, errs
 }
 
-// ListItems returns a slice of all RegistryItems.
-func (r *RegistryActor) ListItems() []RegistryItem {
+// Add a new repository
+func (r *RegistryActor) addRepo(m AddRepo) (RegistryItem, error) {
+	name, path := m.Name, m.Path
 	r.mutex.Lock()
-	defer r.mutex.Unlock()
-	items := make([]RegistryItem, 0, len(r.Repos))
-	for _, repo := range r.Repos {
-		item := RegistryItem{
-			ID:            repo.Name,
-			Name:          repo.Name,
-			Type:          "repository",
-			Status:        "active",
-			Path:          repo.Path,
-			CreatedAt:     time.Now(), // Placeholder
-			LastUpdated:   time.Now(), // Placeholder
-			Enabled:       repo.Active,
-			GitRepo:       nil,         // Placeholder
-			HasDockerfile: repo.IsDocker,
-		}
-		items = append(items, item)
+	if _, exists := r.Repos[name]; exists {
+		r.mutex.Unlock()
+		fmt.Printf("Repository '%s' already exists.\n", name)
+		return RegistryItem{}, fmt.Errorf("%w: %s", ErrRepoExists, name)
 	}
-	return items
+	repo := NewRepoActor(name, path, r.wg)
+	repo.URL = m.URL
+	repo.cloneOpts = m.Clone
+	repo.Parent = m.Parent
+	if m.Kind != "" {
+		repo.Kind = m.Kind
+	}
+	repo.detect()
+	state := repo.state()
+	if err := r.journal.Append(JournalEntry{Type: JournalAddRepo, Repo: name, State: &state}); err != nil {
+		r.mutex.Unlock()
+		return RegistryItem{}, fmt.Errorf("failed to journal repository '%s': %w", name, err)
+	}
+	r.spawn(repo)
+	r.mutex.Unlock()
+	fmt.Printf("Repository '%s' added.\n", name)
+
+	// Initialize the repo. The mailbox was just created, so this cannot block.
+	if err := repo.Mailbox.Send(context.Background(), InitRepo{}); err != nil {
+		return repo.item(), fmt.Errorf("failed to initialize repository '%s': %w", name, err)
+	}
+	return repo.item(), nil
 }
 
-// CoordinatorActor manages dependencies and graph-based progression (Optional)
-type CoordinatorActor struct {
-	Graph      map[string][]string // Dependencies: key depends on values
-	Completed  map[string]bool
-	MsgChan    chan RepoCompleted
-	wg         *sync.WaitGroup
-	registry   *RegistryActor
-	mutex      sync.Mutex
+// Remove a repository
+func (r *RegistryActor) removeRepo(name string) error {
+	r.mutex.Lock()
+	repo, exists := r.Repos[name]
+	if !exists {
+		r.mutex.Unlock()
+		fmt.Printf("Repository '%s' not found.\n", name)
+		return fmt.Errorf("%w: %s", ErrRepoNotFound, name)
+	}
+	if err := r.journal.Append(JournalEntry{Type: JournalRemoveRepo, Repo: name}); err != nil {
+		r.mutex.Unlock()
+		return fmt.Errorf("failed to journal removal of '%s': %w", name, err)
+	}
+	delete(r.Repos, name)
+	r.mutex.Unlock()
+
+	// The actor is unreachable now; let it finish what is queued and stop.
+	repo.Mailbox.Send(context.Background(), ReportCompletion{Name: name})
+	repo.Mailbox.Close()
+	fmt.Printf("Repository '%s' removed.\n", name)
+	return nil
 }
 
-// RepoCompleted message signifies a repo has completed its task
-type RepoCompleted struct {
-	Name string
+// Toggle a repository's active state
+func (r *RegistryActor) toggleRepo(ctx context.Context, name string) (ToggleResult, error) {
+	repo, err := r.lookup(name)
+	if err != nil {
+		fmt.Printf("Repository '%s' not found for toggling.\n", name)
+		return ToggleResult{}, err
+	}
+	result, err := Ask(ctx, repo.Mailbox, ToggleRepo{Name: name})
+	if err != nil {
+		return ToggleResult{}, err
+	}
+	return result.(ToggleResult), nil
 }
 
-// NewCoordinatorActor initializes a new CoordinatorActor
-func NewCoordinatorActor(wg *sync.WaitGroup, registry *RegistryActor) *CoordinatorActor {
-	return &CoordinatorActor{
-		Graph:     make(map[string][]string),
-		Completed: make(map[string]bool),
-		MsgChan:   make(chan RepoCompleted),
-		wg:        wg,
-		registry:  registry,
+// Refresh the Git status of a repository
+func (r *RegistryActor) refreshStatus(ctx context.Context, name string) (GitStatus, error) {
+	repo, err := r.lookup(name)
+	if err != nil {
+		return GitStatus{}, err
+	}
+	status, err := Ask(ctx, repo.Mailbox, RefreshStatus{Name: name})
+	if err != nil {
+		return GitStatus{}, err
 	}
+	return status.(GitStatus), nil
 }
 
-// Start launches the CoordinatorActor's goroutine
-func (c *CoordinatorActor) Start() {
-	c.wg.Add(1)
-	go func() {
-		defer c.wg.Done()
-		for msg := range c.MsgChan {
-			c.handleCompletion(msg)
-		}
-	}()
+// Configure a repository
+func (r *RegistryActor) configureRepo(ctx context.Context, name string) (ConfigureResult, error) {
+	repo, err := r.lookup(name)
+	if err != nil {
+		fmt.Printf("Repository '%s' not found for configuration.\n", name)
+		return ConfigureResult{}, err
+	}
+	// Example: Configure Docker and Pipeline
+	result := ConfigureResult{Name: name}
+	isDocker, err := Ask(ctx, repo.Mailbox, ConfigureDocker{})
+	if err != nil {
+		return result, err
+	}
+	result.IsDocker = isDocker.(bool)
+	hasPipeline, err := Ask(ctx, repo.Mailbox, ConfigurePipeline{})
+	if err != nil {
+		return result, err
+	}
+	result.HasPipeline = hasPipeline.(bool)
+	return result, nil
 }
 
-// AddDependency adds a dependency to the graph
-func (c *CoordinatorActor) AddDependency(repo string, dependsOn []string) {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-	c.Graph[repo] = dependsOn
+// lookup returns the RepoActor registered under name.
+func (r *RegistryActor) lookup(name string) (*RepoActor, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	repo, exists := r.Repos[name]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrRepoNotFound, name)
+	}
+	return repo, nil
 }
 
-// handleCompletion processes the completion of a repository task
-func (c *CoordinatorActor) handleCompletion(msg RepoCompleted) {
-	c.mutex.Lock()
-	c.Completed[msg.Name] = true
-	fmt.Printf("Coordinator: Repository '%s' completed.\n", msg.Name)
+// registeredAt returns the RepoActor registered for the repository at path,
+// under whatever name, or nil.
+func (r *RegistryActor) registeredAt(path string) *RepoActor {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, repo := range r.Repos {
+		if filepath.Clean(repo.Path) == filepath.Clean(path) {
+			return repo
+		}
+	}
+	return nil
+}
 
-	// Check which repositories can now proceed
-	for repo, deps := range c.Graph {
-		if c.Completed[repo] {
-			continue // Already completed
+// Scan a directory for repositories. Found repositories are queued on the
+// actor's own mailbox and added after the scan returns the number found.
+// Repositories already registered are skipped, and those whose name is taken
+// by one at another path are reported in an error wrapping ErrRepoExists.
+func (r *RegistryActor) scanDirectory(directory string, opts DiscoveryOptions) (int, error) {
+	fmt.Printf("Scanning directory '%s' for repositories...\n", directory)
+	discovered, err := Discover(directory, opts)
+	if err != nil {
+		fmt.Printf("Error scanning directory: %v\n", err)
+		return 0, err
+	}
+	var collisions []error
+	for _, repo := range discovered {
+		if r.registeredAt(repo.Path) != nil {
+			continue
 		}
-		allDepsMet := true
-		for _, dep := range deps {
-			if !c.Completed[dep] {
-				allDepsMet = false
-				break
-			}
+		if existing, err := r.lookup(repo.Name); err == nil {
+			fmt.Printf("Repository at '%s' not added: the name '%s' is taken by '%s'.\n", repo.Path, repo.Name, existing.Path)
+			collisions = append(collisions, fmt.Errorf("%w: %s at '%s'", ErrRepoExists, repo.Name, repo.Path))
+			continue
 		}
-		if allDepsMet {
-			fmt.Printf("Coordinator: All dependencies met for '%s'. Proceeding...\n", repo)
-			// Send a message to configure the repo
-			c.registry.Repos[repo].MsgChan <- ConfigureDocker{}
-			c.registry.Repos[repo].MsgChan <- ConfigurePipeline{}
-			c.Completed[repo] = true // Mark as processed
+		msg := AddRepo{Name: repo.Name, Path: repo.Path, Kind: repo.Kind, Parent: repo.Parent}
+		if err := r.Mailbox.SendSelf(msg); err != nil {
+			return 0, err
 		}
 	}
+	return len(discovered), errors.Join(collisions...)
+}
+
+// ListItems returns a slice of all RegistryItems.
+func (r *RegistryActor) ListItems() []RegistryItem {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	items := make([]RegistryItem, 0, len(r.Repos))
+	for _, repo := range r.Repos {
+		items = append(items, repo.item())
+	}
+	return items
+}
 
-	c.mutex.Unlock()
-}
\ No newline at end of file
+// item describes the repository as a RegistryItem.
+func (r *RepoActor) item() RegistryItem {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return RegistryItem{
+		ID:            r.Name,
+		Name:          r.Name,
+		Type:          string(r.Kind),
+		Parent:        r.Parent,
+		Status:        r.status,
+		Path:          r.Path,
+		URL:           r.URL,
+		InitErr:       r.initErr,
+		CreatedAt:     r.CreatedAt,
+		LastUpdated:   r.LastUpdated,
+		Enabled:       r.Active,
+		GitRepo:       r.gitRepo,
+		GitStatus:     r.gitStatus,
+		GitErr:        r.gitErr,
+		HasDockerfile: r.IsDocker,
+		Manifest:      r.Manifest,
+		ManifestErr:   r.manifestErr,
+	}
+}
@@ -0,0 +1,51 @@
+// actor_test.go
+package registry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAskToggleRepo(t *testing.T) {
+	wg := &sync.WaitGroup{}
+	actor := NewRegistryActor(wg)
+	actor.Start()
+	defer actor.Mailbox.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := Ask(ctx, actor.Mailbox, AddRepo{Name: "demo", Path: t.TempDir()}); err != nil {
+		t.Fatalf("AddRepo failed: %v", err)
+	}
+
+	result, err := Ask(ctx, actor.Mailbox, ToggleRepo{Name: "demo"})
+	if err != nil {
+		t.Fatalf("ToggleRepo failed: %v", err)
+	}
+	if toggled := result.(ToggleResult); toggled.Active {
+		t.Errorf("Expected repository to be inactive after toggle")
+	}
+
+	_, err = Ask(ctx, actor.Mailbox, ToggleRepo{Name: "missing"})
+	if !errors.Is(err, ErrRepoNotFound) {
+		t.Errorf("Expected ErrRepoNotFound, got %v", err)
+	}
+}
+
+func TestAskTimeout(t *testing.T) {
+	// Nobody reads this full mailbox, so the request can never be delivered.
+	mailbox := NewMailbox(1, Block)
+	mailbox.Send(context.Background(), Sync{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := Ask(ctx, mailbox, ToggleRepo{Name: "demo"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+// File: registry/ask.go
+package registry
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// DefaultAskTimeout bounds how long callers wait for an actor to reply.
+const DefaultAskTimeout = 10 * time.Second
+
+// Errors returned by the registry actors.
+var (
+	ErrRepoNotFound = errors.New("repository not found")
+	ErrRepoExists   = errors.New("repository already exists")
+	ErrRepoInactive = errors.New("repository is not active")
+)
+
+// Reply carries the result of a request back to the caller.
+type Reply struct {
+	Value interface{}
+	Err   error
+}
+
+// Request wraps a message sent with Ask so the receiving actor can answer it.
+// Messages sent without a Request are fire-and-forget.
+type Request struct {
+	Ctx     context.Context
+	Msg     Message
+	ReplyTo chan Reply
+}
+
+// ToggleResult is the reply to a ToggleRepo request.
+type ToggleResult struct {
+	Name   string
+	Active bool
+}
+
+// ConfigureResult is the reply to a ConfigureRepo request.
+type ConfigureResult struct {
+	Name        string
+	IsDocker    bool
+	HasPipeline bool
+}
+
+// Ask sends msg to an actor mailbox and waits for its reply. It gives up when
+// ctx is cancelled or its deadline expires, either before the actor accepted
+// the message or while waiting for the answer.
+func Ask(ctx context.Context, mailbox *Mailbox, msg Message) (interface{}, error) {
+	req := Request{Ctx: ctx, Msg: msg, ReplyTo: make(chan Reply, 1)}
+
+	if err := mailbox.Send(ctx, req); err != nil {
+		return nil, err
+	}
+
+	select {
+	case reply := <-req.ReplyTo:
+		return reply.Value, reply.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// unwrap splits an incoming message into its context, payload and a function
+// answering the sender. Fire-and-forget messages get a no-op reply function.
+func unwrap(msg Message) (context.Context, Message, func(interface{}, error)) {
+	req, ok := msg.(Request)
+	if !ok {
+		return context.Background(), msg, func(interface{}, error) {}
+	}
+	ctx := req.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return ctx, req.Msg, func(value interface{}, err error) {
+		// ReplyTo is buffered, so answering never blocks the actor even if
+		// the caller already gave up.
+		req.ReplyTo <- Reply{Value: value, Err: err}
+	}
+}
@@ -0,0 +1,337 @@
+// File: registry/branch.go
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+var (
+	ErrDirtyWorktree    = errors.New("worktree has uncommitted changes")
+	ErrBranchNotFound   = errors.New("branch not found")
+	ErrBranchExists     = errors.New("branch already exists")
+	ErrBranchCheckedOut = errors.New("branch is checked out")
+)
+
+// BranchAction is an operation on a branch across repositories.
+type BranchAction string
+
+const (
+	BranchCreate BranchAction = "create" // Create the branch at HEAD and check it out
+	BranchSwitch BranchAction = "switch" // Check out the branch, tracking origin if only it has the branch
+	BranchDelete BranchAction = "delete" // Delete the branch
+)
+
+// BranchOptions selects the repositories a branch action applies to.
+type BranchOptions struct {
+	Repos []string // Repository names; every repository when empty
+	Group string   // Only repositories in this manifest group
+}
+
+// BranchChange records what a branch action did to one repository, so it
+// can be rolled back.
+type BranchChange struct {
+	Repo     string
+	Action   BranchAction
+	Branch   string
+	Previous string         // Reference or commit HEAD pointed at before the change
+	Created  bool           // The branch was created by the change
+	Hash     string         // Commit a deleted branch pointed at
+	Upstream *config.Branch // Tracking config of a deleted branch, if any
+}
+
+// BranchView shows whether a repository has a branch and has it checked out.
+type BranchView struct {
+	Repo       string
+	Current    string // Checked out branch, empty when HEAD is detached
+	Exists     bool
+	CheckedOut bool
+	Err        error
+}
+
+// ChangeBranch asks a RepoActor to apply a branch action. The reply is a
+// BranchChange.
+type ChangeBranch struct {
+	Action BranchAction
+	Name   string
+}
+
+// RevertBranch asks a RepoActor to undo a BranchChange.
+type RevertBranch struct {
+	Change BranchChange
+}
+
+// ReadBranch asks a RepoActor about a branch. The reply is a BranchView.
+type ReadBranch struct {
+	Name string
+}
+
+// Branch applies action to the branch called name in every selected
+// repository, one repository at a time in name order. If any repository
+// fails, the repositories already changed are rolled back and the error
+// names the repository that failed.
+func (r *Registry) Branch(ctx context.Context, action BranchAction, name string, opts BranchOptions) ([]BranchChange, error) {
+	switch action {
+	case BranchCreate, BranchSwitch, BranchDelete:
+	default:
+		return nil, fmt.Errorf("unknown branch action '%s'", action)
+	}
+	if name == "" || strings.HasPrefix(name, "-") || strings.Contains(name, "..") || strings.ContainsAny(name, " ~^:?*[\\") {
+		return nil, fmt.Errorf("invalid branch name '%s'", name)
+	}
+	names := r.selectRepos(opts.Repos, opts.Group)
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no repositories selected")
+	}
+
+	var applied []BranchChange
+	for _, repoName := range names {
+		repo, err := r.RegistryActor.lookup(repoName)
+		var change interface{}
+		if err == nil {
+			change, err = Ask(ctx, repo.Mailbox, ChangeBranch{Action: action, Name: name})
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to %s branch '%s' in '%s': %w", action, name, repoName, err)
+			return nil, errors.Join(err, r.rollbackBranches(applied))
+		}
+		applied = append(applied, change.(BranchChange))
+	}
+	return applied, nil
+}
+
+// rollbackBranches undoes changes in reverse order. It uses its own timeout
+// so a rollback still runs when the caller's context has expired.
+func (r *Registry) rollbackBranches(changes []BranchChange) error {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultAskTimeout)
+	defer cancel()
+	var errs []error
+	for i := len(changes) - 1; i >= 0; i-- {
+		change := changes[i]
+		repo, err := r.RegistryActor.lookup(change.Repo)
+		if err == nil {
+			_, err = Ask(ctx, repo.Mailbox, RevertBranch{Change: change})
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to roll back '%s': %w", change.Repo, err))
+			continue
+		}
+		fmt.Printf("Rolled back branch %s in '%s'\n", change.Action, change.Repo)
+	}
+	return errors.Join(errs...)
+}
+
+// Branches reports, for every selected repository, whether it has the
+// branch called name and whether it is checked out.
+func (r *Registry) Branches(ctx context.Context, name string, opts BranchOptions) []BranchView {
+	names := r.selectRepos(opts.Repos, opts.Group)
+	views := make([]BranchView, len(names))
+	var wg sync.WaitGroup
+	for i, repoName := range names {
+		wg.Add(1)
+		go func(i int, repoName string) {
+			defer wg.Done()
+			views[i] = BranchView{Repo: repoName}
+			repo, err := r.RegistryActor.lookup(repoName)
+			var view interface{}
+			if err == nil {
+				view, err = Ask(ctx, repo.Mailbox, ReadBranch{Name: name})
+			}
+			if err != nil {
+				views[i].Err = err
+				return
+			}
+			views[i] = view.(BranchView)
+		}(i, repoName)
+	}
+	wg.Wait()
+	return views
+}
+
+// selectRepos returns the named repositories, or every repository when
+// repos is empty, limited to group when it is set. Names are sorted.
+func (r *Registry) selectRepos(repos []string, group string) []string {
+	names := append([]string(nil), repos...)
+	if len(names) == 0 {
+		names = r.RegistryActor.Names()
+	}
+	if group != "" {
+		inGroup := make(map[string]bool)
+		for _, name := range r.Group(group) {
+			inGroup[name] = true
+		}
+		selected := names[:0]
+		for _, name := range names {
+			if inGroup[name] {
+				selected = append(selected, name)
+			}
+		}
+		names = selected
+	}
+	sort.Strings(names)
+	return names
+}
+
+// changeBranch applies a branch action to the repository.
+func (r *RepoActor) changeBranch(action BranchAction, name string) (BranchChange, error) {
+	change := BranchChange{Repo: r.Name, Action: action, Branch: name}
+	r.mu.RLock()
+	repo := r.gitRepo
+	r.mu.RUnlock()
+	if repo == nil {
+		return change, fmt.Errorf("%w: %s", ErrNotGitRepo, r.Path)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return change, fmt.Errorf("failed to read HEAD: %w", err)
+	}
+	change.Previous = head.Hash().String()
+	if head.Name().IsBranch() {
+		change.Previous = head.Name().String()
+	}
+	ref := plumbing.NewBranchReferenceName(name)
+	existing, err := repo.Reference(ref, true)
+	exists := err == nil
+
+	switch action {
+	case BranchCreate:
+		if exists {
+			return change, fmt.Errorf("%w: %s", ErrBranchExists, name)
+		}
+		if err := r.checkout(repo, &git.CheckoutOptions{Branch: ref, Hash: head.Hash(), Create: true}); err != nil {
+			return change, err
+		}
+		change.Created = true
+
+	case BranchSwitch:
+		if head.Name() == ref {
+			break
+		}
+		opts := &git.CheckoutOptions{Branch: ref}
+		if !exists {
+			remote, err := repo.Reference(plumbing.NewRemoteReferenceName(git.DefaultRemoteName, name), true)
+			if err != nil {
+				return change, fmt.Errorf("%w: %s", ErrBranchNotFound, name)
+			}
+			opts.Hash = remote.Hash()
+			opts.Create = true
+		}
+		if err := r.checkout(repo, opts); err != nil {
+			return change, err
+		}
+		change.Created = opts.Create
+
+	case BranchDelete:
+		if !exists {
+			return change, fmt.Errorf("%w: %s", ErrBranchNotFound, name)
+		}
+		if head.Name() == ref {
+			return change, fmt.Errorf("%w: %s", ErrBranchCheckedOut, name)
+		}
+		change.Hash = existing.Hash().String()
+		// The tracking config goes too, so a branch created with the same
+		// name later does not inherit its upstream.
+		if upstream, err := repo.Branch(name); err == nil {
+			if err := repo.DeleteBranch(name); err != nil {
+				return change, fmt.Errorf("failed to delete config of branch '%s': %w", name, err)
+			}
+			change.Upstream = upstream
+		}
+		if err := repo.Storer.RemoveReference(ref); err != nil {
+			if change.Upstream != nil {
+				repo.CreateBranch(change.Upstream)
+			}
+			return change, fmt.Errorf("failed to delete branch '%s': %w", name, err)
+		}
+	}
+
+	fmt.Printf("Branch %s '%s' in repo '%s'\n", action, name, r.Name)
+	r.refreshStatus()
+	return change, nil
+}
+
+// revertBranch undoes a change made by changeBranch.
+func (r *RepoActor) revertBranch(change BranchChange) error {
+	r.mu.RLock()
+	repo := r.gitRepo
+	r.mu.RUnlock()
+	if repo == nil {
+		return fmt.Errorf("%w: %s", ErrNotGitRepo, r.Path)
+	}
+	ref := plumbing.NewBranchReferenceName(change.Branch)
+
+	switch change.Action {
+	case BranchCreate, BranchSwitch:
+		opts := &git.CheckoutOptions{Branch: plumbing.ReferenceName(change.Previous)}
+		if !strings.HasPrefix(change.Previous, "refs/") {
+			opts = &git.CheckoutOptions{Hash: plumbing.NewHash(change.Previous)}
+		}
+		worktree, err := repo.Worktree()
+		if err != nil {
+			return fmt.Errorf("failed to open worktree: %w", err)
+		}
+		if err := worktree.Checkout(opts); err != nil {
+			return fmt.Errorf("failed to check out '%s': %w", change.Previous, err)
+		}
+		if change.Created {
+			if err := repo.Storer.RemoveReference(ref); err != nil {
+				return fmt.Errorf("failed to delete branch '%s': %w", change.Branch, err)
+			}
+		}
+	case BranchDelete:
+		if err := repo.Storer.SetReference(plumbing.NewHashReference(ref, plumbing.NewHash(change.Hash))); err != nil {
+			return fmt.Errorf("failed to restore branch '%s': %w", change.Branch, err)
+		}
+		if change.Upstream != nil {
+			if err := repo.CreateBranch(change.Upstream); err != nil {
+				return fmt.Errorf("failed to restore config of branch '%s': %w", change.Branch, err)
+			}
+		}
+	}
+	r.refreshStatus()
+	return nil
+}
+
+// readBranch reports whether the repository has the branch called name.
+func (r *RepoActor) readBranch(name string) (BranchView, error) {
+	view := BranchView{Repo: r.Name}
+	status, err := r.refreshStatus()
+	if err != nil {
+		return view, err
+	}
+	r.mu.RLock()
+	repo := r.gitRepo
+	r.mu.RUnlock()
+	_, err = repo.Reference(plumbing.NewBranchReferenceName(name), false)
+	view.Current = status.Branch
+	view.Exists = err == nil
+	view.CheckedOut = status.Branch == name
+	return view, nil
+}
+
+// checkout switches the worktree, refusing when it has uncommitted changes.
+func (r *RepoActor) checkout(repo *git.Repository, opts *git.CheckoutOptions) error {
+	status, err := ReadGitStatus(repo)
+	if err != nil {
+		return err
+	}
+	if status.Dirty {
+		return fmt.Errorf("%w: %s", ErrDirtyWorktree, r.Name)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to open worktree: %w", err)
+	}
+	if err := worktree.Checkout(opts); err != nil {
+		return fmt.Errorf("failed to check out '%s': %w", opts.Branch.Short(), err)
+	}
+	return nil
+}
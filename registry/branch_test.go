@@ -0,0 +1,116 @@
+// branch_test.go
+package registry
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// currentBranches returns the checked out branch of each repository.
+func currentBranches(t *testing.T, reg *Registry, ctx context.Context, name string) map[string]BranchView {
+	t.Helper()
+	views := make(map[string]BranchView)
+	for _, view := range reg.Branches(ctx, name, BranchOptions{}) {
+		if view.Err != nil {
+			t.Fatalf("Reading branches of '%s' failed: %v", view.Repo, view.Err)
+		}
+		views[view.Repo] = view
+	}
+	return views
+}
+
+func TestRegistryBranch(t *testing.T) {
+	projects := t.TempDir()
+	for _, name := range []string{"a", "b", "c"} {
+		repo, _ := git.PlainOpen(writeRepo(t, projects, name, nil))
+		commitFile(t, repo, "README.md", name)
+	}
+
+	reg, err := NewRegistry(WithProjectsPath(projects), WithStatePath(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	defer reg.Shutdown(ctx)
+
+	if _, err := reg.Branch(ctx, BranchCreate, "bad name", BranchOptions{}); err == nil {
+		t.Error("Expected an invalid branch name to be refused")
+	}
+
+	changes, err := reg.Branch(ctx, BranchCreate, "feature", BranchOptions{Repos: []string{"b", "a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 || changes[0].Repo != "a" || changes[0].Previous != "refs/heads/master" {
+		t.Errorf("Unexpected changes %+v", changes)
+	}
+	views := currentBranches(t, reg, ctx, "feature")
+	if !views["a"].CheckedOut || !views["b"].CheckedOut || views["c"].Exists || views["c"].Current != "master" {
+		t.Errorf("Expected a and b on feature, got %+v", views)
+	}
+
+	// c has no feature branch, so a and b are switched back.
+	if _, err := reg.Branch(ctx, BranchSwitch, "master", BranchOptions{Repos: []string{"a", "b"}}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = reg.Branch(ctx, BranchSwitch, "feature", BranchOptions{})
+	if !errors.Is(err, ErrBranchNotFound) {
+		t.Errorf("Expected the missing branch in c to fail the switch, got %v", err)
+	}
+	views = currentBranches(t, reg, ctx, "feature")
+	for _, name := range []string{"a", "b"} {
+		if views[name].Current != "master" || !views[name].Exists {
+			t.Errorf("Expected '%s' to be rolled back to master, got %+v", name, views[name])
+		}
+	}
+
+	// A dirty c rolls back the creation in a and b, deleting the new branch.
+	os.WriteFile(filepath.Join(projects, "c", "README.md"), []byte("dirty"), 0644)
+	_, err = reg.Branch(ctx, BranchCreate, "other", BranchOptions{})
+	if !errors.Is(err, ErrDirtyWorktree) {
+		t.Errorf("Expected the dirty worktree to fail the create, got %v", err)
+	}
+	for name, view := range currentBranches(t, reg, ctx, "other") {
+		if view.Exists || view.Current != "master" {
+			t.Errorf("Expected '%s' to be rolled back, got %+v", name, view)
+		}
+	}
+
+	// Deleting is refused while checked out, and the deletion in a is undone.
+	repoA, _ := git.PlainOpen(filepath.Join(projects, "a"))
+	upstream := &config.Branch{Name: "feature", Remote: "origin", Merge: plumbing.NewBranchReferenceName("feature")}
+	if err := repoA.CreateBranch(upstream); err != nil {
+		t.Fatal(err)
+	}
+	reg.Branch(ctx, BranchSwitch, "feature", BranchOptions{Repos: []string{"b"}})
+	_, err = reg.Branch(ctx, BranchDelete, "feature", BranchOptions{Repos: []string{"a", "b"}})
+	if !errors.Is(err, ErrBranchCheckedOut) {
+		t.Errorf("Expected deleting a checked out branch to fail, got %v", err)
+	}
+	if _, err := repoA.Reference(plumbing.NewBranchReferenceName("feature"), false); err != nil {
+		t.Errorf("Expected the deleted branch in 'a' to be restored: %v", err)
+	}
+	if restored, err := repoA.Branch("feature"); err != nil || restored.Remote != "origin" {
+		t.Errorf("Expected the tracking config in 'a' to be restored, got %+v, %v", restored, err)
+	}
+
+	// Deleting removes the tracking config with the branch.
+	if _, err := reg.Branch(ctx, BranchSwitch, "master", BranchOptions{Repos: []string{"b"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reg.Branch(ctx, BranchDelete, "feature", BranchOptions{Repos: []string{"a", "b"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repoA.Branch("feature"); !errors.Is(err, git.ErrBranchNotFound) {
+		t.Errorf("Expected the tracking config of the deleted branch to be gone, got %v", err)
+	}
+}
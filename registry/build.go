@@ -0,0 +1,166 @@
+// File: registry/build.go
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/pkg/jsonmessage"
+)
+
+// ErrBuildFailed is returned when Docker reports a failed image build.
+var ErrBuildFailed = errors.New("image build failed")
+
+// BuildEventType identifies what happened during an image build.
+type BuildEventType string
+
+const (
+	BuildStep    BuildEventType = "step"   // A Dockerfile instruction started
+	BuildCached  BuildEventType = "cached" // The current step's layer came from the cache
+	BuildOutput  BuildEventType = "output" // A line of output
+	BuildImageID BuildEventType = "image"  // The image was built
+	BuildError   BuildEventType = "error"  // Docker reported an error
+)
+
+// BuildEvent is one thing that happened during an image build.
+type BuildEvent struct {
+	Type    BuildEventType
+	Repo    string
+	Step    int    // Current step, from 1; zero before the first
+	Steps   int    // Number of steps, when known
+	Message string // Instruction, output line or error message
+	ImageID string // Set for BuildImageID
+	Code    int    // Error code reported by Docker, for BuildError
+}
+
+// stepPattern matches the line starting a step of the classic builder.
+var stepPattern = regexp.MustCompile(`^Step (\d+)/(\d+) : (.*)$`)
+
+// Build is an image build in progress.
+type Build struct {
+	Repo    string
+	Tags    []string // Image references the build is tagged with
+	events  chan BuildEvent
+	done    chan struct{}
+	imageID string
+	err     error
+}
+
+func newBuild(repo string) *Build {
+	return &Build{
+		Repo:   repo,
+		events: make(chan BuildEvent, 16),
+		done:   make(chan struct{}),
+	}
+}
+
+// Events returns the build's events. The channel is closed when the build
+// ends.
+func (b *Build) Events() <-chan BuildEvent {
+	return b.events
+}
+
+// Wait discards the events not received yet, waits for the build to end and
+// returns the ID of the image built.
+func (b *Build) Wait() (string, error) {
+	for range b.events {
+	}
+	<-b.done
+	return b.imageID, b.err
+}
+
+// read turns Docker's build output into events until it ends. Events are
+// dropped once ctx is done so an abandoned build does not block.
+func (b *Build) read(ctx context.Context, output io.Reader) error {
+	defer close(b.done)
+	defer close(b.events)
+	b.imageID, b.err = parseBuildOutput(output, func(event BuildEvent) {
+		event.Repo = b.Repo
+		select {
+		case b.events <- event:
+		case <-ctx.Done():
+		}
+	})
+	return b.err
+}
+
+// parseBuildOutput reads the JSON messages of a Docker build, passing each
+// as an event to emit, and returns the ID of the image built. An error in
+// the output, or output ending without an image, is returned as an error.
+func parseBuildOutput(output io.Reader, emit func(BuildEvent)) (string, error) {
+	var (
+		step, steps int
+		imageID     string
+		failure     error
+		partial     string // Output line not terminated yet
+	)
+	lines := func(text string) {
+		text = partial + text
+		complete := strings.Split(text, "\n")
+		partial = complete[len(complete)-1]
+		for _, line := range complete[:len(complete)-1] {
+			line = strings.TrimRight(line, "\r")
+			switch match := stepPattern.FindStringSubmatch(line); {
+			case strings.TrimSpace(line) == "":
+			case match != nil:
+				step, _ = strconv.Atoi(match[1])
+				steps, _ = strconv.Atoi(match[2])
+				emit(BuildEvent{Type: BuildStep, Step: step, Steps: steps, Message: match[3]})
+			case strings.TrimSpace(line) == "---> Using cache":
+				emit(BuildEvent{Type: BuildCached, Step: step, Steps: steps})
+			default:
+				emit(BuildEvent{Type: BuildOutput, Step: step, Steps: steps, Message: line})
+			}
+		}
+	}
+
+	decoder := json.NewDecoder(output)
+	for {
+		var msg jsonmessage.JSONMessage
+		err := decoder.Decode(&msg)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return imageID, fmt.Errorf("failed to read build output: %w", err)
+		}
+		switch {
+		case msg.Error != nil || msg.ErrorMessage != "":
+			message, code := msg.ErrorMessage, 0
+			if msg.Error != nil {
+				message, code = msg.Error.Message, msg.Error.Code
+			}
+			emit(BuildEvent{Type: BuildError, Step: step, Steps: steps, Message: message, Code: code})
+			failure = fmt.Errorf("%w: %s", ErrBuildFailed, message)
+		case msg.Aux != nil:
+			var aux struct{ ID string }
+			if err := json.Unmarshal(*msg.Aux, &aux); err == nil && aux.ID != "" {
+				imageID = aux.ID
+				emit(BuildEvent{Type: BuildImageID, Step: step, Steps: steps, ImageID: imageID})
+			}
+		case msg.Stream != "":
+			lines(msg.Stream)
+		case msg.Status != "":
+			status := msg.Status
+			if msg.ID != "" {
+				status = msg.ID + ": " + status
+			}
+			emit(BuildEvent{Type: BuildOutput, Step: step, Steps: steps, Message: status})
+		}
+	}
+	lines("\n")
+
+	if failure != nil {
+		return imageID, failure
+	}
+	if imageID == "" {
+		return "", fmt.Errorf("%w: the build ended without an image", ErrBuildFailed)
+	}
+	return imageID, nil
+}
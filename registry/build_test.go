@@ -0,0 +1,123 @@
+// build_test.go
+package registry
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseBuildOutput(t *testing.T) {
+	output := strings.Join([]string{
+		`{"stream":"Step 1/3 : FROM alpine"}`,
+		`{"stream":"\n"}`,
+		`{"status":"Pulling fs layer","id":"abc"}`,
+		`{"stream":" ---> Using cache\n"}`,
+		`{"stream":"Step 2/3 : RUN echo hi\n"}`,
+		`{"stream":"hi\nthere\n"}`,
+		`{"stream":"Step 3/3 : CMD [\"sh\"]\n"}`,
+		`{"aux":{"ID":"sha256:1234"}}`,
+		`{"stream":"Successfully built 1234\n"}`,
+	}, "\n")
+
+	var events []BuildEvent
+	imageID, err := parseBuildOutput(strings.NewReader(output), func(e BuildEvent) {
+		events = append(events, e)
+	})
+	if err != nil || imageID != "sha256:1234" {
+		t.Fatalf("Expected image sha256:1234, got %q, %v", imageID, err)
+	}
+	want := []BuildEvent{
+		{Type: BuildStep, Step: 1, Steps: 3, Message: "FROM alpine"},
+		{Type: BuildOutput, Step: 1, Steps: 3, Message: "abc: Pulling fs layer"},
+		{Type: BuildCached, Step: 1, Steps: 3},
+		{Type: BuildStep, Step: 2, Steps: 3, Message: "RUN echo hi"},
+		{Type: BuildOutput, Step: 2, Steps: 3, Message: "hi"},
+		{Type: BuildOutput, Step: 2, Steps: 3, Message: "there"},
+		{Type: BuildStep, Step: 3, Steps: 3, Message: `CMD ["sh"]`},
+		{Type: BuildImageID, Step: 3, Steps: 3, ImageID: "sha256:1234"},
+		{Type: BuildOutput, Step: 3, Steps: 3, Message: "Successfully built 1234"},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("Expected %d events, got %+v", len(want), events)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Errorf("Event %d: expected %+v, got %+v", i, want[i], events[i])
+		}
+	}
+}
+
+func TestParseBuildOutputFailures(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{
+			"error in stream",
+			`{"stream":"Step 1/1 : RUN false\n"}` + "\n" + `{"errorDetail":{"code":1,"message":"The command returned a non-zero code: 1"},"error":"The command returned a non-zero code: 1"}`,
+			"non-zero code",
+		},
+		{"no image", `{"stream":"Step 1/1 : FROM scratch\n"}`, "without an image"},
+		{"bad json", `{"stream":`, "failed to read build output"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseBuildOutput(strings.NewReader(tt.output), func(BuildEvent) {})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestBuildEvents(t *testing.T) {
+	build := newBuild("app")
+	output := `{"errorDetail":{"code":2,"message":"boom"}}`
+	go build.read(context.Background(), strings.NewReader(output))
+
+	var events []BuildEvent
+	for event := range build.Events() {
+		events = append(events, event)
+	}
+	if len(events) != 1 || events[0].Type != BuildError || events[0].Repo != "app" || events[0].Code != 2 {
+		t.Errorf("Expected one error event for 'app', got %+v", events)
+	}
+	if _, err := build.Wait(); !errors.Is(err, ErrBuildFailed) {
+		t.Errorf("Expected ErrBuildFailed, got %v", err)
+	}
+}
+
+func TestBuildOptions(t *testing.T) {
+	repo := t.TempDir()
+	os.MkdirAll(filepath.Join(repo, "service", "build"), 0755)
+	os.WriteFile(filepath.Join(repo, "service", "build", "Dockerfile.dev"), []byte("FROM scratch\n"), 0644)
+
+	contextDir, options, err := buildOptions(repo, BuildProfile{
+		Dockerfile: "service/build/Dockerfile.dev",
+		Context:    "service",
+		Target:     "debug",
+		Args:       map[string]string{"DEBUG": "1"},
+		Labels:     map[string]string{"team": "platform"},
+		CacheFrom:  []string{"example/api:cache"},
+		NoCache:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contextDir != filepath.Join(repo, "service") || options.Dockerfile != "build/Dockerfile.dev" {
+		t.Errorf("Unexpected context %q and Dockerfile %q", contextDir, options.Dockerfile)
+	}
+	if options.Target != "debug" || *options.BuildArgs["DEBUG"] != "1" || options.Labels["team"] != "platform" ||
+		options.CacheFrom[0] != "example/api:cache" || !options.NoCache {
+		t.Errorf("Unexpected options %+v", options)
+	}
+
+	if _, _, err := buildOptions(repo, BuildProfile{}); err == nil {
+		t.Error("Expected an error for a repository without a Dockerfile")
+	}
+}
@@ -0,0 +1,191 @@
+// File: registry/buildcontext.go
+package registry
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/moby/patternmatcher"
+	"github.com/moby/patternmatcher/ignorefile"
+)
+
+// DockerIgnoreFile lists the paths left out of a build context.
+const DockerIgnoreFile = ".dockerignore"
+
+// contextModTime is the modification time of every build context entry, so
+// the archive only changes when content or modes do.
+var contextModTime = time.Unix(0, 0).UTC()
+
+// BuildContext streams the directory at dir as a tar archive for a Docker
+// build, leaving out what its .dockerignore excludes apart from the
+// Dockerfile and the .dockerignore itself, or the .git directory when there
+// is no .dockerignore. dockerfile is relative to dir
+// and defaults to "Dockerfile". Entries are written in lexical order with
+// fixed timestamps and ownership, so an unchanged tree always produces the
+// same archive and the build cache is kept. Symbolic links are archived as
+// links. The caller must close the reader; closing it early stops the
+// archive being written.
+func BuildContext(dir, dockerfile string) (io.ReadCloser, error) {
+	if dockerfile == "" {
+		dockerfile = "Dockerfile"
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read build context '%s': %w", dir, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("build context '%s' is not a directory", dir)
+	}
+	matcher, err := readDockerIgnore(dir)
+	if err != nil {
+		return nil, err
+	}
+	keep := map[string]bool{
+		filepath.ToSlash(filepath.Clean(dockerfile)): true,
+		DockerIgnoreFile: true,
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(writeBuildContext(writer, dir, matcher, keep))
+	}()
+	return reader, nil
+}
+
+// readDockerIgnore compiles the .dockerignore patterns of dir. A missing
+// file only excludes .git.
+func readDockerIgnore(dir string) (*patternmatcher.PatternMatcher, error) {
+	file, err := os.Open(filepath.Join(dir, DockerIgnoreFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return patternmatcher.New([]string{".git"})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", DockerIgnoreFile, err)
+	}
+	defer file.Close()
+	patterns, err := ignorefile.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", DockerIgnoreFile, err)
+	}
+	matcher, err := patternmatcher.New(patterns)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern in %s: %w", DockerIgnoreFile, err)
+	}
+	return matcher, nil
+}
+
+// writeBuildContext writes the archive of dir to w.
+func writeBuildContext(w io.Writer, dir string, matcher *patternmatcher.PatternMatcher, keep map[string]bool) error {
+	tw := tar.NewWriter(w)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		name := filepath.ToSlash(rel)
+
+		excluded, err := matcher.MatchesOrParentMatches(name)
+		if err != nil {
+			return err
+		}
+		if excluded && !keep[name] {
+			if d.IsDir() && !mayInclude(matcher, keep, name) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return writeContextEntry(tw, path, name, info)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to archive build context '%s': %w", dir, err)
+	}
+	return tw.Close()
+}
+
+// mayInclude reports whether a kept file or an exception pattern could
+// re-include something inside the excluded directory name.
+func mayInclude(matcher *patternmatcher.PatternMatcher, keep map[string]bool, name string) bool {
+	for kept := range keep {
+		if strings.HasPrefix(kept, name+"/") {
+			return true
+		}
+	}
+	if !matcher.Exclusions() {
+		return false
+	}
+	for _, pattern := range matcher.Patterns() {
+		if !pattern.Exclusion() {
+			continue
+		}
+		// Wildcards may match anywhere below the directory.
+		if strings.ContainsAny(pattern.String(), "*?[") || strings.HasPrefix(pattern.String()+"/", name+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// writeContextEntry archives one file, directory or symbolic link.
+// Sockets, devices and other special files are left out.
+func writeContextEntry(tw *tar.Writer, path, name string, info fs.FileInfo) error {
+	var link string
+	switch mode := info.Mode(); {
+	case mode.IsRegular(), mode.IsDir():
+	case mode&fs.ModeSymlink != 0:
+		target, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+		link = target
+	default:
+		return nil
+	}
+
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	if info.IsDir() {
+		header.Name += "/"
+	}
+	header.ModTime = contextModTime
+	header.AccessTime = time.Time{}
+	header.ChangeTime = time.Time{}
+	header.Uid, header.Gid = 0, 0
+	header.Uname, header.Gname = "", ""
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err := io.CopyN(tw, file, info.Size()); err != nil {
+		return fmt.Errorf("failed to archive '%s': %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+// buildcontext_test.go
+package registry
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readContext returns the archive BuildContext produces for dir and its
+// headers by name.
+func readContext(t *testing.T, dir, dockerfile string) ([]byte, []string, map[string]*tar.Header) {
+	t.Helper()
+	reader, err := BuildContext(dir, dockerfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	headers := make(map[string]*tar.Header)
+	tr := tar.NewReader(bytes.NewReader(data))
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, header.Name)
+		headers[header.Name] = header
+	}
+	return data, names, headers
+}
+
+func TestBuildContext(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"Dockerfile":        "FROM scratch\n",
+		"app.go":            "package main\n",
+		"secret.env":        "TOKEN=x\n",
+		"docs/guide.md":     "guide\n",
+		"docs/keep.md":      "keep\n",
+		"node_modules/x.js": "x\n",
+		".git/HEAD":         "ref: refs/heads/master\n",
+		DockerIgnoreFile:    ".git\nnode_modules\n*.env\ndocs\n!docs/keep.md\nDockerfile\n.dockerignore\n",
+	}
+	for name, content := range files {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755)
+		os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+	}
+	os.WriteFile(filepath.Join(dir, "run.sh"), []byte("#!/bin/sh\n"), 0755)
+	if err := os.Symlink("app.go", filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	first, names, headers := readContext(t, dir, "")
+	want := []string{".dockerignore", "Dockerfile", "app.go", "docs/keep.md", "link", "run.sh"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected %v, got %v", want, names)
+	}
+	if link := headers["link"]; link == nil || link.Typeflag != tar.TypeSymlink || link.Linkname != "app.go" {
+		t.Errorf("Expected 'link' archived as a link to app.go, got %+v", link)
+	}
+	if run := headers["run.sh"]; run == nil || run.Mode&0777 != 0755 {
+		t.Errorf("Expected run.sh to keep mode 0755, got %+v", run)
+	}
+	if app := headers["app.go"]; app == nil || !app.ModTime.Equal(contextModTime) || app.Uid != 0 || app.Uname != "" {
+		t.Errorf("Expected normalized timestamps and ownership, got %+v", app)
+	}
+
+	// Touching files must not change the archive.
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(filepath.Join(dir, "app.go"), later, later)
+	if second, _, _ := readContext(t, dir, ""); !bytes.Equal(first, second) {
+		t.Error("Expected identical archives for an unchanged tree")
+	}
+}
+
+func TestBuildContextDefaults(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, ".git"), 0755)
+	os.WriteFile(filepath.Join(dir, ".git", "HEAD"), []byte("ref: refs/heads/master\n"), 0644)
+	os.MkdirAll(filepath.Join(dir, "build"), 0755)
+	os.WriteFile(filepath.Join(dir, "build", "Dockerfile.dev"), []byte("FROM scratch\n"), 0644)
+
+	_, names, _ := readContext(t, dir, "build/Dockerfile.dev")
+	want := []string{"build/", "build/Dockerfile.dev"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected .git to be left out, got %v", names)
+	}
+
+	if _, err := BuildContext(filepath.Join(dir, "missing"), ""); err == nil {
+		t.Error("Expected an error for a missing directory")
+	}
+}
@@ -0,0 +1,72 @@
+// File: registry/clone.go
+package registry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// CloneOptions controls how a repository added by URL is cloned.
+type CloneOptions struct {
+	Branch   string    // Branch to check out; the remote's HEAD when empty
+	Depth    int       // Number of commits to fetch; full history when zero
+	Progress io.Writer // Receives the remote's progress output, if set
+}
+
+// CloneOptsFunc customizes CloneOptions.
+type CloneOptsFunc func(*CloneOptions)
+
+// WithCloneBranch checks out branch instead of the remote's HEAD.
+func WithCloneBranch(branch string) CloneOptsFunc {
+	return func(o *CloneOptions) {
+		o.Branch = branch
+	}
+}
+
+// WithCloneDepth makes a shallow clone of depth commits.
+func WithCloneDepth(depth int) CloneOptsFunc {
+	return func(o *CloneOptions) {
+		o.Depth = depth
+	}
+}
+
+// WithCloneProgress streams clone progress to w.
+func WithCloneProgress(w io.Writer) CloneOptsFunc {
+	return func(o *CloneOptions) {
+		o.Progress = w
+	}
+}
+
+// scpLikeURL matches Git's scp-style syntax, e.g. git@github.com:user/repo.git.
+var scpLikeURL = regexp.MustCompile(`^[\w.-]+@[\w.-]+:`)
+
+// IsRemoteURL reports whether source is a Git URL rather than a local path.
+// Local repositories can be cloned with a file:// URL.
+func IsRemoteURL(source string) bool {
+	return strings.Contains(source, "://") || scpLikeURL.MatchString(source)
+}
+
+// clone clones the repository's URL into its path.
+func (r *RepoActor) clone(ctx context.Context) error {
+	fmt.Printf("Cloning '%s' into '%s'...\n", r.URL, r.Path)
+	opts := &git.CloneOptions{
+		URL:      r.URL,
+		Depth:    r.cloneOpts.Depth,
+		Progress: r.cloneOpts.Progress,
+	}
+	if r.cloneOpts.Branch != "" {
+		opts.ReferenceName = plumbing.NewBranchReferenceName(r.cloneOpts.Branch)
+		opts.SingleBranch = r.cloneOpts.Depth > 0
+	}
+	if _, err := git.PlainCloneContext(ctx, r.Path, false, opts); err != nil {
+		return fmt.Errorf("failed to clone '%s': %w", r.URL, err)
+	}
+	r.detect()
+	return nil
+}
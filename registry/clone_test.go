@@ -0,0 +1,125 @@
+// clone_test.go
+package registry
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// bareRemote creates a bare repository with commits on master and a
+// "feature" branch, and returns its file:// URL.
+func bareRemote(t *testing.T) string {
+	t.Helper()
+	remote := filepath.Join(t.TempDir(), "remote.git")
+	if _, err := git.PlainInit(remote, true); err != nil {
+		t.Fatal(err)
+	}
+	repo, err := git.PlainInit(t.TempDir(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFile(t, repo, "a.txt", "one")
+	commitFile(t, repo, "a.txt", "two")
+	worktree, _ := repo.Worktree()
+	if err := worktree.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("feature"), Create: true}); err != nil {
+		t.Fatal(err)
+	}
+	commitFile(t, repo, "Dockerfile", "FROM scratch\n")
+	if _, err := repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{remote}}); err != nil {
+		t.Fatal(err)
+	}
+	err = repo.Push(&git.PushOptions{RefSpecs: []config.RefSpec{"refs/heads/*:refs/heads/*"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "file://" + remote
+}
+
+func TestIsRemoteURL(t *testing.T) {
+	for source, want := range map[string]bool{
+		"https://github.com/Cdaprod/repo.git": true,
+		"git@github.com:Cdaprod/repo.git":     true,
+		"file:///srv/git/repo.git":            true,
+		"/home/cdaprod/Projects/repo":         false,
+		"relative/repo":                       false,
+	} {
+		if got := IsRemoteURL(source); got != want {
+			t.Errorf("IsRemoteURL(%q) = %v, want %v", source, got, want)
+		}
+	}
+}
+
+func TestAddRepoClones(t *testing.T) {
+	url := bareRemote(t)
+	projects := t.TempDir()
+	reg, err := NewRegistry(WithProjectsPath(projects), WithStatePath(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	defer reg.Shutdown(ctx)
+
+	var progress bytes.Buffer
+	item, err := reg.AddRepo(ctx, "full", url, WithCloneProgress(&progress))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Path != filepath.Join(projects, "full") || item.URL != url {
+		t.Errorf("Expected a clone into the projects path, got %+v", item)
+	}
+	if item.GitStatus == nil || item.GitStatus.Branch != "master" || item.HasDockerfile {
+		t.Errorf("Expected master to be checked out, got %+v", item.GitStatus)
+	}
+
+	item, err = reg.AddRepo(ctx, "shallow", url, WithCloneBranch("feature"), WithCloneDepth(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.GitStatus == nil || item.GitStatus.Branch != "feature" || !item.HasDockerfile {
+		t.Errorf("Expected the feature branch with its Dockerfile, got %+v", item)
+	}
+	repo, _ := git.PlainOpen(item.Path)
+	commits, _ := repo.Log(&git.LogOptions{})
+	count := 0
+	commits.ForEach(func(*object.Commit) error { count++; return nil })
+	if count != 1 {
+		t.Errorf("Expected a shallow clone with 1 commit, got %d", count)
+	}
+}
+
+func TestAddRepoCloneFailure(t *testing.T) {
+	projects := t.TempDir()
+	reg, err := NewRegistry(WithProjectsPath(projects), WithStatePath(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	defer reg.Shutdown(ctx)
+
+	missing := "file://" + filepath.Join(t.TempDir(), "missing.git")
+	item, err := reg.AddRepo(ctx, "broken", missing)
+	if err == nil {
+		t.Fatal("Expected cloning a missing remote to fail")
+	}
+	if item.InitErr == nil || !strings.HasPrefix(item.Status, "failed:") {
+		t.Errorf("Expected the repository to be left failed, got %+v", item)
+	}
+	if _, err := os.Stat(filepath.Join(projects, "broken", ".git")); err == nil {
+		t.Error("Expected no repository to be left behind")
+	}
+	if _, err := reg.Item("broken"); err != nil {
+		t.Errorf("Expected the failed repository to stay registered: %v", err)
+	}
+}
@@ -0,0 +1,606 @@
+// File: registry/coordinator.go
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultConcurrency is the number of repositories a run works on at once
+// when RunOptions does not say otherwise.
+const DefaultConcurrency = 4
+
+// ErrDependencyCycle is returned when a dependency would make the graph cyclic.
+var ErrDependencyCycle = errors.New("dependency cycle")
+
+// NodeStatus is the state of a repository within a run.
+type NodeStatus string
+
+const (
+	NodePending   NodeStatus = "pending"
+	NodeRunning   NodeStatus = "running"
+	NodeSucceeded NodeStatus = "succeeded"
+	NodeFailed    NodeStatus = "failed"
+	NodeSkipped   NodeStatus = "skipped"
+)
+
+// Task is the work a run performs for each repository.
+type Task func(ctx context.Context, repo string) error
+
+// RunOptions configures a scheduler run.
+type RunOptions struct {
+	// Repos limits the run to these repositories and their dependencies.
+	// When empty every repository in the graph or registry is run.
+	Repos []string
+	// Concurrency caps how many independent repositories run in parallel.
+	Concurrency int
+	// Task is executed for each repository once its dependencies succeeded.
+	// It defaults to configuring the repository through the RegistryActor.
+	Task Task
+}
+
+// NodeResult is the outcome of one repository within a run.
+type NodeResult struct {
+	Name     string
+	Status   NodeStatus
+	Err      error
+	Started  time.Time
+	Finished time.Time
+}
+
+// Run tracks one execution of the dependency graph.
+type Run struct {
+	ID     string
+	Order  []string
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu      sync.Mutex
+	results map[string]*NodeResult
+}
+
+// Done is closed once every repository in the run has finished or was skipped.
+func (r *Run) Done() <-chan struct{} {
+	return r.done
+}
+
+// Cancel stops the run. Running tasks see their context cancelled and
+// repositories that have not started are skipped.
+func (r *Run) Cancel() {
+	r.cancel()
+}
+
+// Wait blocks until the run is finished and returns an error naming the
+// repositories that failed.
+func (r *Run) Wait(ctx context.Context) error {
+	select {
+	case <-r.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	var failed []string
+	for _, result := range r.Results() {
+		if result.Status == NodeFailed {
+			failed = append(failed, result.Name)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("run %s: %d repositories failed: %s", r.ID, len(failed), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// Results returns the per-repository outcomes in topological order.
+func (r *Run) Results() []NodeResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	results := make([]NodeResult, 0, len(r.Order))
+	for _, name := range r.Order {
+		results = append(results, *r.results[name])
+	}
+	return results
+}
+
+// StartRun asks the coordinator to begin a run. The reply is the *Run.
+type StartRun struct {
+	Options RunOptions
+}
+
+// RepoCompleted message signifies a repo has completed its task within a run
+type RepoCompleted struct {
+	RunID string
+	Name  string
+	Err   error
+}
+
+// runState is the coordinator's bookkeeping for an active run.
+type runState struct {
+	run         *Run
+	ctx         context.Context
+	task        Task
+	concurrency int
+	graph       map[string][]string // Dependencies restricted to the run
+	dependents  map[string][]string
+	waiting     map[string]int // Unfinished dependencies per repository
+	ready       []string
+	running     int
+	finished    int
+}
+
+// CoordinatorActor schedules work over the repository dependency graph.
+type CoordinatorActor struct {
+	Graph    map[string][]string // Dependencies: key depends on values
+	edges    map[string][]Edge   // Graph with the source of each edge
+	Mailbox  *Mailbox
+	wg       *sync.WaitGroup
+	registry *RegistryActor
+	runs     map[string]*runState
+	nextRun  int
+	ctx      context.Context // Parent of every run's context
+	done     chan struct{}
+	metrics  *Metrics
+	mutex    sync.Mutex
+}
+
+// NewCoordinatorActor initializes a new CoordinatorActor
+func NewCoordinatorActor(wg *sync.WaitGroup, registry *RegistryActor) *CoordinatorActor {
+	return &CoordinatorActor{
+		Graph:    make(map[string][]string),
+		edges:    make(map[string][]Edge),
+		Mailbox:  NewMailbox(DefaultMailboxCapacity, Block),
+		wg:       wg,
+		registry: registry,
+		runs:     make(map[string]*runState),
+		ctx:      context.Background(),
+		done:     make(chan struct{}),
+	}
+}
+
+// SetContext sets the context runs are derived from. Cancelling it cancels
+// every run. It must be called before Start.
+func (c *CoordinatorActor) SetContext(ctx context.Context) {
+	c.ctx = ctx
+}
+
+// SetMetrics makes the coordinator record metrics. It must be called before
+// Start.
+func (c *CoordinatorActor) SetMetrics(metrics *Metrics) {
+	c.metrics = metrics
+}
+
+// Done is closed once the coordinator's mailbox is closed and drained.
+func (c *CoordinatorActor) Done() <-chan struct{} {
+	return c.done
+}
+
+// Start launches the CoordinatorActor's goroutine
+func (c *CoordinatorActor) Start() {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer close(c.done)
+		for {
+			msg, ok := c.Mailbox.Receive()
+			if !ok {
+				c.abortRuns()
+				return
+			}
+			ctx, m, reply := unwrap(msg)
+			if err := ctx.Err(); err != nil {
+				reply(nil, err)
+				continue
+			}
+			start := time.Now()
+			value, err := c.receive(m)
+			c.metrics.ObserveMessage("coordinator", m, start, err)
+			reply(value, err)
+		}
+	}()
+}
+
+// receive handles a single message and returns the result for the sender.
+func (c *CoordinatorActor) receive(msg Message) (interface{}, error) {
+	switch m := msg.(type) {
+	case StartRun:
+		return c.startRun(m.Options)
+	case RepoCompleted:
+		c.handleCompletion(m)
+		return nil, nil
+	case Sync:
+		return nil, nil
+	default:
+		fmt.Printf("Coordinator received unknown message: %v\n", msg)
+		return nil, fmt.Errorf("coordinator received unknown message %T", msg)
+	}
+}
+
+// AddDependency records that repo depends on dependsOn, replacing its
+// previous dependencies. It refuses changes that would create a cycle.
+func (c *CoordinatorActor) AddDependency(repo string, dependsOn []string) error {
+	return c.SetEdges(repo, edgesFrom(repo, dependsOn, SourceManual))
+}
+
+// SetEdges replaces the dependencies of repo with edges, keeping where each
+// one came from. It refuses changes that would create a cycle.
+func (c *CoordinatorActor) SetEdges(repo string, edges []Edge) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var deps []string
+	seen := make(map[string]bool)
+	for _, edge := range edges {
+		if !seen[edge.To] {
+			seen[edge.To] = true
+			deps = append(deps, edge.To)
+		}
+	}
+
+	previous, existed := c.Graph[repo]
+	c.Graph[repo] = deps
+	if cycle := findCycle(c.Graph); cycle != nil {
+		if existed {
+			c.Graph[repo] = previous
+		} else {
+			delete(c.Graph, repo)
+		}
+		return fmt.Errorf("%w: %s", ErrDependencyCycle, strings.Join(cycle, " -> "))
+	}
+	if len(edges) == 0 {
+		delete(c.Graph, repo)
+		delete(c.edges, repo)
+		return nil
+	}
+	c.edges[repo] = append([]Edge(nil), edges...)
+	return nil
+}
+
+// Dependencies returns a copy of the dependency graph.
+func (c *CoordinatorActor) Dependencies() map[string][]string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	graph := make(map[string][]string, len(c.Graph))
+	for repo, deps := range c.Graph {
+		graph[repo] = append([]string(nil), deps...)
+	}
+	return graph
+}
+
+// Edges returns a copy of every repository's dependency edges with their
+// sources.
+func (c *CoordinatorActor) Edges() map[string][]Edge {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	edges := make(map[string][]Edge, len(c.edges))
+	for repo, repoEdges := range c.edges {
+		edges[repo] = append([]Edge(nil), repoEdges...)
+	}
+	return edges
+}
+
+// manual reports whether any dependency of repo was added by hand.
+func (c *CoordinatorActor) manual(repo string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, edge := range c.edges[repo] {
+		if edge.Source == SourceManual {
+			return true
+		}
+	}
+	return false
+}
+
+// Order returns repos and their transitive dependencies in topological
+// order, dependencies first. An empty repos orders the whole graph.
+func (c *CoordinatorActor) Order(repos []string) ([]string, error) {
+	c.mutex.Lock()
+	graph := subgraph(c.Graph, repos)
+	c.mutex.Unlock()
+	return topoSort(graph)
+}
+
+// startRun validates the graph and starts executing it.
+func (c *CoordinatorActor) startRun(opts RunOptions) (*Run, error) {
+	repos := opts.Repos
+	if len(repos) == 0 {
+		repos = c.registry.Names()
+		c.mutex.Lock()
+		for repo := range c.Graph {
+			repos = append(repos, repo)
+		}
+		c.mutex.Unlock()
+	}
+
+	c.mutex.Lock()
+	graph := subgraph(c.Graph, repos)
+	c.mutex.Unlock()
+	order, err := topoSort(graph)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Concurrency < 1 {
+		opts.Concurrency = DefaultConcurrency
+	}
+	if opts.Task == nil {
+		opts.Task = c.configureTask
+	}
+
+	c.nextRun++
+	ctx, cancel := context.WithCancel(c.ctx)
+	run := &Run{
+		ID:      fmt.Sprintf("run-%s-%d", time.Now().Format("20060102-150405"), c.nextRun),
+		Order:   order,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		results: make(map[string]*NodeResult, len(order)),
+	}
+	state := &runState{
+		run:         run,
+		ctx:         ctx,
+		task:        opts.Task,
+		concurrency: opts.Concurrency,
+		graph:       graph,
+		dependents:  make(map[string][]string),
+		waiting:     make(map[string]int),
+	}
+	for _, name := range order {
+		run.results[name] = &NodeResult{Name: name, Status: NodePending}
+		state.waiting[name] = len(graph[name])
+		for _, dep := range graph[name] {
+			state.dependents[dep] = append(state.dependents[dep], name)
+		}
+		if len(graph[name]) == 0 {
+			state.ready = append(state.ready, name)
+		}
+	}
+	c.runs[run.ID] = state
+
+	fmt.Printf("Coordinator: starting %s over %d repositories.\n", run.ID, len(order))
+	c.dispatch(state)
+	return run, nil
+}
+
+// dispatch starts ready repositories up to the run's concurrency limit.
+func (c *CoordinatorActor) dispatch(state *runState) {
+	for state.running < state.concurrency && len(state.ready) > 0 {
+		name := state.ready[0]
+		state.ready = state.ready[1:]
+
+		if err := state.ctx.Err(); err != nil {
+			c.finish(state, name, NodeSkipped, err)
+			continue
+		}
+
+		state.running++
+		state.run.mu.Lock()
+		result := state.run.results[name]
+		result.Status = NodeRunning
+		result.Started = time.Now()
+		state.run.mu.Unlock()
+
+		c.wg.Add(1)
+		go func(runID, name string) {
+			defer c.wg.Done()
+			err := runTask(state.ctx, state.task, name)
+			c.Mailbox.Send(context.Background(), RepoCompleted{RunID: runID, Name: name, Err: err})
+		}(state.run.ID, name)
+	}
+	c.checkDone(state)
+}
+
+// handleCompletion processes the completion of a repository task
+func (c *CoordinatorActor) handleCompletion(msg RepoCompleted) {
+	state, exists := c.runs[msg.RunID]
+	if !exists {
+		fmt.Printf("Coordinator: completion for unknown run '%s'.\n", msg.RunID)
+		return
+	}
+	state.running--
+
+	if msg.Err != nil {
+		fmt.Printf("Coordinator: Repository '%s' failed: %v\n", msg.Name, msg.Err)
+		c.finish(state, msg.Name, NodeFailed, msg.Err)
+	} else {
+		fmt.Printf("Coordinator: Repository '%s' completed.\n", msg.Name)
+		c.finish(state, msg.Name, NodeSucceeded, nil)
+	}
+	c.dispatch(state)
+}
+
+// finish records a terminal status for name and updates its dependents:
+// they become ready once all their dependencies succeeded, and are skipped
+// as soon as one of them did not.
+func (c *CoordinatorActor) finish(state *runState, name string, status NodeStatus, err error) {
+	state.run.mu.Lock()
+	result := state.run.results[name]
+	result.Status = status
+	result.Err = err
+	result.Finished = time.Now()
+	state.run.mu.Unlock()
+	state.finished++
+
+	for _, dependent := range state.dependents[name] {
+		if status != NodeSucceeded {
+			state.run.mu.Lock()
+			pending := state.run.results[dependent].Status == NodePending
+			state.run.mu.Unlock()
+			if pending {
+				c.finish(state, dependent, NodeSkipped, fmt.Errorf("dependency '%s' %s", name, status))
+			}
+			continue
+		}
+		state.waiting[dependent]--
+		if state.waiting[dependent] == 0 {
+			state.ready = append(state.ready, dependent)
+		}
+	}
+}
+
+// checkDone closes the run once every repository has a terminal status.
+func (c *CoordinatorActor) checkDone(state *runState) {
+	if state.running > 0 || state.finished < len(state.run.Order) {
+		return
+	}
+	delete(c.runs, state.run.ID)
+	state.run.cancel()
+	close(state.run.done)
+	fmt.Printf("Coordinator: %s finished.\n", state.run.ID)
+}
+
+// abortRuns finishes every run still in progress once the mailbox is closed:
+// completions can no longer arrive, so running repositories are marked failed
+// and the rest skipped.
+func (c *CoordinatorActor) abortRuns() {
+	for _, state := range c.runs {
+		state.run.cancel()
+		state.run.mu.Lock()
+		for _, result := range state.run.results {
+			switch result.Status {
+			case NodeRunning:
+				result.Status = NodeFailed
+				result.Err = ErrShutdown
+				result.Finished = time.Now()
+			case NodePending:
+				result.Status = NodeSkipped
+				result.Err = ErrShutdown
+			}
+		}
+		state.run.mu.Unlock()
+		close(state.run.done)
+		delete(c.runs, state.run.ID)
+	}
+}
+
+// configureTask is the default Task: it configures Docker and the pipeline
+// for the repository through the RegistryActor.
+func (c *CoordinatorActor) configureTask(ctx context.Context, repo string) error {
+	_, err := Ask(ctx, c.registry.Mailbox, ConfigureRepo{Name: repo})
+	return err
+}
+
+// runTask executes task, turning a panic into an error.
+func runTask(ctx context.Context, task Task, repo string) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("task for '%s' panicked: %v", repo, p)
+		}
+	}()
+	return task(ctx, repo)
+}
+
+// subgraph returns the dependencies of repos and everything they depend on.
+// An empty repos selects the whole graph.
+func subgraph(graph map[string][]string, repos []string) map[string][]string {
+	if len(repos) == 0 {
+		for repo := range graph {
+			repos = append(repos, repo)
+		}
+	}
+	sub := make(map[string][]string)
+	var visit func(string)
+	visit = func(repo string) {
+		if _, seen := sub[repo]; seen {
+			return
+		}
+		deps := append([]string{}, graph[repo]...)
+		sub[repo] = deps
+		for _, dep := range deps {
+			visit(dep)
+		}
+	}
+	for _, repo := range repos {
+		visit(repo)
+	}
+	return sub
+}
+
+// topoSort orders graph dependencies first. Repositories that become ready
+// at the same time are ordered by name so runs are reproducible.
+func topoSort(graph map[string][]string) ([]string, error) {
+	waiting := make(map[string]int, len(graph))
+	dependents := make(map[string][]string)
+	var ready []string
+	for repo, deps := range graph {
+		waiting[repo] = len(deps)
+		for _, dep := range deps {
+			dependents[dep] = append(dependents[dep], repo)
+		}
+		if len(deps) == 0 {
+			ready = append(ready, repo)
+		}
+	}
+
+	order := make([]string, 0, len(graph))
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		repo := ready[0]
+		ready = ready[1:]
+		order = append(order, repo)
+		for _, dependent := range dependents[repo] {
+			waiting[dependent]--
+			if waiting[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	if len(order) < len(graph) {
+		cycle := findCycle(graph)
+		return nil, fmt.Errorf("%w: %s", ErrDependencyCycle, strings.Join(cycle, " -> "))
+	}
+	return order, nil
+}
+
+// findCycle returns the repositories forming a cycle, first repository
+// repeated at the end, or nil if the graph is acyclic.
+func findCycle(graph map[string][]string) []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int)
+	var stack []string
+
+	var visit func(string) []string
+	visit = func(repo string) []string {
+		state[repo] = visiting
+		stack = append(stack, repo)
+		for _, dep := range graph[repo] {
+			switch state[dep] {
+			case visiting:
+				for i, name := range stack {
+					if name == dep {
+						return append(append([]string{}, stack[i:]...), dep)
+					}
+				}
+			case unvisited:
+				if cycle := visit(dep); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[repo] = visited
+		return nil
+	}
+
+	repos := make([]string, 0, len(graph))
+	for repo := range graph {
+		repos = append(repos, repo)
+	}
+	sort.Strings(repos)
+	for _, repo := range repos {
+		if state[repo] == unvisited {
+			if cycle := visit(repo); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
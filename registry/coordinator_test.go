@@ -0,0 +1,134 @@
+// coordinator_test.go
+package registry
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestCoordinator(t *testing.T) *CoordinatorActor {
+	wg := &sync.WaitGroup{}
+	coordinator := NewCoordinatorActor(wg, NewRegistryActor(wg))
+	coordinator.Start()
+	t.Cleanup(coordinator.Mailbox.Close)
+	return coordinator
+}
+
+func TestCoordinatorRejectsCycles(t *testing.T) {
+	coordinator := newTestCoordinator(t)
+
+	if err := coordinator.AddDependency("api", []string{"lib"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := coordinator.AddDependency("lib", []string{"core"}); err != nil {
+		t.Fatal(err)
+	}
+	err := coordinator.AddDependency("core", []string{"api"})
+	if !errors.Is(err, ErrDependencyCycle) {
+		t.Fatalf("Expected ErrDependencyCycle, got %v", err)
+	}
+	if want := "dependency cycle: api -> lib -> core -> api"; err.Error() != want {
+		t.Errorf("Expected %q, got %q", want, err.Error())
+	}
+	if _, exists := coordinator.Dependencies()["core"]; exists {
+		t.Error("Expected the rejected dependency to be reverted")
+	}
+}
+
+func TestCoordinatorRunsInDependencyOrder(t *testing.T) {
+	coordinator := newTestCoordinator(t)
+	coordinator.AddDependency("app", []string{"api", "web"})
+	coordinator.AddDependency("api", []string{"core"})
+	coordinator.AddDependency("web", []string{"core"})
+
+	order, err := coordinator.Order(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"core", "api", "web", "app"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("Expected order %v, got %v", want, order)
+	}
+
+	var mu sync.Mutex
+	var running, peak int32
+	finished := make(map[string]bool)
+	task := func(ctx context.Context, repo string) error {
+		mu.Lock()
+		for _, dep := range coordinator.Dependencies()[repo] {
+			if !finished[dep] {
+				t.Errorf("'%s' started before its dependency '%s' finished", repo, dep)
+			}
+		}
+		mu.Unlock()
+		if n := atomic.AddInt32(&running, 1); n > atomic.LoadInt32(&peak) {
+			atomic.StoreInt32(&peak, n)
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		mu.Lock()
+		finished[repo] = true
+		mu.Unlock()
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result, err := Ask(ctx, coordinator.Mailbox, StartRun{Options: RunOptions{Concurrency: 2, Task: task}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := result.(*Run)
+	if err := run.Wait(ctx); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if peak != 2 {
+		t.Errorf("Expected api and web to run in parallel, peak concurrency was %d", peak)
+	}
+	for _, r := range run.Results() {
+		if r.Status != NodeSucceeded {
+			t.Errorf("Expected '%s' to succeed, got %s", r.Name, r.Status)
+		}
+	}
+}
+
+func TestCoordinatorSkipsDependentsOfFailures(t *testing.T) {
+	coordinator := newTestCoordinator(t)
+	coordinator.AddDependency("app", []string{"api"})
+	coordinator.AddDependency("api", []string{"core"})
+	coordinator.AddDependency("docs", nil)
+
+	task := func(ctx context.Context, repo string) error {
+		if repo == "core" {
+			panic("boom")
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result, err := Ask(ctx, coordinator.Mailbox, StartRun{Options: RunOptions{Task: task}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := result.(*Run)
+	if err := run.Wait(ctx); err == nil {
+		t.Fatal("Expected the run to report the failed repository")
+	}
+
+	want := map[string]NodeStatus{
+		"core": NodeFailed,
+		"api":  NodeSkipped,
+		"app":  NodeSkipped,
+		"docs": NodeSucceeded,
+	}
+	for _, r := range run.Results() {
+		if r.Status != want[r.Name] {
+			t.Errorf("Expected '%s' to be %s, got %s (%v)", r.Name, want[r.Name], r.Status, r.Err)
+		}
+	}
+}
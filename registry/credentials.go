@@ -0,0 +1,171 @@
+// File: registry/credentials.go
+package registry
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	registrytypes "github.com/docker/docker/api/types/registry"
+)
+
+// dockerHubHost is the host of images named without one.
+const dockerHubHost = "docker.io"
+
+// dockerHubAuthKey is the key Docker's config keeps Docker Hub credentials
+// under.
+const dockerHubAuthKey = "https://index.docker.io/v1/"
+
+// dockerConfig is the part of Docker's config.json holding credentials.
+type dockerConfig struct {
+	Auths       map[string]dockerAuth `json:"auths"`
+	CredsStore  string                `json:"credsStore"`
+	CredHelpers map[string]string     `json:"credHelpers"`
+}
+
+// dockerAuth is one entry of a Docker config's auths.
+type dockerAuth struct {
+	Auth          string `json:"auth"`
+	Username      string `json:"username"`
+	Password      string `json:"password"`
+	IdentityToken string `json:"identitytoken"`
+	RegistryToken string `json:"registrytoken"`
+}
+
+// dockerConfigPath returns the config.json the docker CLI uses.
+func dockerConfigPath() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return filepath.Join(dir, "config.json")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".docker", "config.json")
+}
+
+// referenceHost returns the registry host of an image reference.
+func referenceHost(ref string) string {
+	first, _, found := strings.Cut(ref, "/")
+	if !found || (!strings.ContainsAny(first, ".:") && first != "localhost") {
+		return dockerHubHost
+	}
+	return first
+}
+
+// normalizeHost turns a Docker config key such as
+// "https://index.docker.io/v1/" into the host it stands for.
+func normalizeHost(key string) string {
+	host := strings.TrimPrefix(strings.TrimPrefix(key, "https://"), "http://")
+	host, _, _ = strings.Cut(host, "/")
+	if host == "index.docker.io" || host == "registry-1.docker.io" {
+		return dockerHubHost
+	}
+	return host
+}
+
+// pushCredentials returns the credentials for pushing to host: those in the
+// registry's config when set, otherwise Docker's, including its credential
+// helpers. No credentials is not an error.
+func (r *Registry) pushCredentials(host string) (registrytypes.AuthConfig, error) {
+	if r.Config.PushUsername != "" {
+		return registrytypes.AuthConfig{
+			Username:      r.Config.PushUsername,
+			Password:      r.Config.PushPassword,
+			ServerAddress: host,
+		}, nil
+	}
+	return dockerCredentials(dockerConfigPath(), host)
+}
+
+// dockerCredentials reads the credentials for host from the Docker config
+// at path.
+func dockerCredentials(path, host string) (registrytypes.AuthConfig, error) {
+	none := registrytypes.AuthConfig{ServerAddress: host}
+	if path == "" {
+		return none, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return none, nil
+	}
+	if err != nil {
+		return none, fmt.Errorf("failed to read docker config: %w", err)
+	}
+	var config dockerConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return none, fmt.Errorf("failed to parse docker config %s: %w", path, err)
+	}
+
+	serverURL := host
+	if host == dockerHubHost {
+		serverURL = dockerHubAuthKey
+	}
+	if helper := config.CredHelpers[host]; helper != "" {
+		return helperCredentials(helper, serverURL, host)
+	}
+	if config.CredsStore != "" {
+		return helperCredentials(config.CredsStore, serverURL, host)
+	}
+
+	for key, auth := range config.Auths {
+		if normalizeHost(key) != host {
+			continue
+		}
+		credentials := registrytypes.AuthConfig{
+			Username:      auth.Username,
+			Password:      auth.Password,
+			IdentityToken: auth.IdentityToken,
+			RegistryToken: auth.RegistryToken,
+			ServerAddress: host,
+		}
+		if auth.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+			if err != nil {
+				return none, fmt.Errorf("invalid auth for %s in docker config: %w", key, err)
+			}
+			username, password, found := strings.Cut(string(decoded), ":")
+			if !found {
+				return none, fmt.Errorf("invalid auth for %s in docker config", key)
+			}
+			credentials.Username, credentials.Password = username, password
+		}
+		return credentials, nil
+	}
+	return none, nil
+}
+
+// helperCredentials asks the docker-credential-<helper> program for the
+// credentials of serverURL. A helper that knows none is not an error.
+func helperCredentials(helper, serverURL, host string) (registrytypes.AuthConfig, error) {
+	none := registrytypes.AuthConfig{ServerAddress: host}
+	cmd := exec.Command("docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(serverURL)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		message := strings.TrimSpace(stdout.String() + stderr.String())
+		if strings.Contains(message, "credentials not found") {
+			return none, nil
+		}
+		return none, fmt.Errorf("failed to run credential helper %s: %w: %s", helper, err, message)
+	}
+	var reply struct {
+		Username string
+		Secret   string
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &reply); err != nil {
+		return none, fmt.Errorf("invalid reply from credential helper %s: %w", helper, err)
+	}
+	// Helpers return identity tokens under this user name.
+	if reply.Username == "<token>" {
+		return registrytypes.AuthConfig{IdentityToken: reply.Secret, ServerAddress: host}, nil
+	}
+	return registrytypes.AuthConfig{Username: reply.Username, Password: reply.Secret, ServerAddress: host}, nil
+}
@@ -0,0 +1,405 @@
+// File: registry/discovery.go
+package registry
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// RepoKind describes how a discovered repository relates to others.
+type RepoKind string
+
+const (
+	KindRepository RepoKind = "repository" // A standalone repository
+	KindWorktree   RepoKind = "worktree"   // A linked worktree of another repository
+	KindSubmodule  RepoKind = "submodule"  // A submodule checked out inside its parent
+	KindNested     RepoKind = "nested"     // An independent repository inside another, e.g. vendored
+)
+
+// DiscoveredRepo is a repository found on disk.
+type DiscoveredRepo struct {
+	Name   string // Path relative to root, or "<parent>/<relative path>" for children
+	Path   string
+	Kind   RepoKind
+	Parent string // Name of the enclosing or main repository, if any
+}
+
+// DefaultDiscoveryDepth is how many directories below the projects path
+// discovery looks for repositories.
+const DefaultDiscoveryDepth = 4
+
+// IgnoreFile holds gitignore-style patterns of directories discovery skips.
+// It is read from every directory discovery visits.
+const IgnoreFile = ".registryignore"
+
+// progressInterval limits how often discovery reports progress.
+const progressInterval = 100 * time.Millisecond
+
+// DiscoveryOptions controls how Discover walks the file system.
+type DiscoveryOptions struct {
+	MaxDepth int      // Directory levels below root to visit; unlimited when zero
+	Include  []string // Globs a repository's path relative to root, or its base name, must match when set
+	Exclude  []string // Globs of directories to skip, matched like Include
+	Nested   bool     // Keep looking for nested repositories inside the repositories found
+	Progress func(DiscoveryProgress)
+}
+
+// DiscoveryProgress reports how far discovery has got.
+type DiscoveryProgress struct {
+	Dirs  int    // Directories visited
+	Found int    // Repositories found
+	Path  string // Directory being visited
+	Done  bool
+}
+
+// found is a repository during discovery, before it is named.
+type found struct {
+	path     string
+	kind     RepoKind
+	parent   int    // Index of the parent, -1 for none
+	mainRepo string // Main repository path of a worktree
+	name     string
+}
+
+// discovery is the state of one walk.
+type discovery struct {
+	root     string
+	opts     DiscoveryOptions
+	repos    []*found
+	visited  map[string]bool // Real paths of the directories visited
+	searched []string        // Directories visited that are not repositories
+	ignore   []gitignore.Pattern
+	dirs     int
+	reported time.Time
+}
+
+// Discover finds the Git repositories under root, including root itself.
+// Once a repository other than root is found Discover does not descend into
+// it, except to pick up the submodules it declares, unless opts.Nested is
+// set.
+// Repositories inside another repository are reported as its children:
+// submodules and nested repositories by their enclosing repository, and
+// linked worktrees by the repository they belong to when it is under root
+// too. Symbolic links to directories are followed, but every directory is
+// visited once.
+func Discover(root string, opts DiscoveryOptions) ([]DiscoveredRepo, error) {
+	discovered, _, err := discover(root, opts)
+	return discovered, err
+}
+
+// discover is Discover, also returning the directories searched that are
+// not repositories.
+func discover(root string, opts DiscoveryOptions) ([]DiscoveredRepo, []string, error) {
+	d := &discovery{root: root, opts: opts, visited: make(map[string]bool)}
+	if err := d.walk(root, 0, -1); err != nil {
+		return nil, nil, fmt.Errorf("failed to discover repositories in '%s': %w", root, err)
+	}
+	d.report(root, true)
+	repos := d.repos
+
+	// Worktrees belong to their main repository wherever it was found.
+	for _, repo := range repos {
+		if repo.kind != KindWorktree {
+			continue
+		}
+		for i, other := range repos {
+			if other.kind != KindWorktree && samePath(other.path, repo.mainRepo) {
+				repo.parent = i
+			}
+		}
+	}
+
+	discovered := make([]DiscoveredRepo, 0, len(repos))
+	for _, repo := range repos {
+		item := DiscoveredRepo{Name: d.nameOf(repos, repo), Path: repo.path, Kind: repo.kind}
+		if repo.parent >= 0 {
+			item.Parent = d.nameOf(repos, repos[repo.parent])
+		}
+		discovered = append(discovered, item)
+	}
+	return discovered, d.searched, nil
+}
+
+// walk visits the directory at path, depth levels below root, inside the
+// repository at index enclosing, or -1.
+func (d *discovery) walk(path string, depth, enclosing int) error {
+	real, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		if path == d.root {
+			return err
+		}
+		return nil
+	}
+	if d.visited[real] {
+		return nil
+	}
+	d.visited[real] = true
+	d.dirs++
+	d.report(path, false)
+
+	rel := d.relative(path)
+	if path != d.root && (d.ignored(rel) || matchAny(d.opts.Exclude, rel)) {
+		return nil
+	}
+	d.readIgnoreFile(path, rel)
+
+	if repo := d.repository(path, enclosing); repo != nil {
+		if len(d.opts.Include) > 0 && !matchAny(d.opts.Include, rel) {
+			return nil
+		}
+		d.repos = append(d.repos, repo)
+		enclosing = len(d.repos) - 1
+		d.submodules(path, enclosing)
+		if !d.opts.Nested {
+			if path != d.root {
+				return nil
+			}
+			// A projects directory kept in Git still holds projects of
+			// its own.
+			enclosing = -1
+		}
+	} else {
+		d.searched = append(d.searched, path)
+	}
+	if d.opts.MaxDepth > 0 && depth >= d.opts.MaxDepth {
+		return nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		if path == d.root {
+			return err
+		}
+		return nil
+	}
+	for _, entry := range entries {
+		if entry.Name() == ".git" {
+			continue
+		}
+		child := filepath.Join(path, entry.Name())
+		if entry.Type()&fs.ModeSymlink != 0 {
+			if info, err := os.Stat(child); err != nil || !info.IsDir() {
+				continue
+			}
+		} else if !entry.IsDir() {
+			continue
+		}
+		if err := d.walk(child, depth+1, enclosing); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// repository classifies the directory at path, or returns nil when it is
+// not a repository.
+func (d *discovery) repository(path string, enclosing int) *found {
+	info, err := os.Lstat(filepath.Join(path, ".git"))
+	if err != nil {
+		return nil
+	}
+	repo := &found{path: path, kind: KindRepository, parent: enclosing}
+	if enclosing >= 0 {
+		repo.kind = KindNested
+	}
+	if !info.IsDir() {
+		gitDir, commonDir, err := gitDirs(path)
+		if err != nil {
+			return nil
+		}
+		switch {
+		case commonDir != gitDir:
+			repo.kind = KindWorktree
+			repo.parent = -1
+			repo.mainRepo = filepath.Dir(commonDir)
+		case strings.Contains(filepath.ToSlash(gitDir), "/modules/"):
+			repo.kind = KindSubmodule
+		}
+	}
+	return repo
+}
+
+// submodules adds the checked out submodules declared in the .gitmodules
+// file of the repository at index parent, and their own submodules.
+func (d *discovery) submodules(dir string, parent int) {
+	content, err := os.ReadFile(filepath.Join(dir, ".gitmodules"))
+	if err != nil {
+		return
+	}
+	modules := config.NewModules()
+	if err := modules.Unmarshal(content); err != nil {
+		return
+	}
+	paths := make([]string, 0, len(modules.Submodules))
+	for _, module := range modules.Submodules {
+		paths = append(paths, filepath.Join(dir, filepath.FromSlash(module.Path)))
+	}
+	sort.Strings(paths)
+	for _, sub := range paths {
+		real, err := filepath.EvalSymlinks(sub)
+		if err != nil || d.visited[real] || !within(dir, sub) {
+			continue
+		}
+		repo := d.repository(sub, parent)
+		if repo == nil {
+			continue
+		}
+		d.visited[real] = true
+		repo.kind = KindSubmodule
+		d.repos = append(d.repos, repo)
+		d.submodules(sub, len(d.repos)-1)
+	}
+}
+
+// readIgnoreFile adds the patterns of the ignore file in the directory at
+// dir, which is rel relative to root.
+func (d *discovery) readIgnoreFile(dir, rel string) {
+	content, err := os.ReadFile(filepath.Join(dir, IgnoreFile))
+	if err != nil {
+		return
+	}
+	var domain []string
+	if rel != "." {
+		domain = strings.Split(rel, "/")
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		d.ignore = append(d.ignore, gitignore.ParsePattern(line, domain))
+	}
+}
+
+// ignored reports whether the ignore files exclude the directory rel.
+func (d *discovery) ignored(rel string) bool {
+	if len(d.ignore) == 0 {
+		return false
+	}
+	return gitignore.NewMatcher(d.ignore).Match(strings.Split(rel, "/"), true)
+}
+
+// relative returns path relative to root with forward slashes.
+func (d *discovery) relative(path string) string {
+	rel, err := filepath.Rel(d.root, path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// report passes progress to the callback, at most every progressInterval
+// until discovery is done.
+func (d *discovery) report(path string, done bool) {
+	if d.opts.Progress == nil {
+		return
+	}
+	now := time.Now()
+	if !done && now.Sub(d.reported) < progressInterval {
+		return
+	}
+	d.reported = now
+	d.opts.Progress(DiscoveryProgress{Dirs: d.dirs, Found: len(d.repos), Path: path, Done: done})
+}
+
+// matchAny reports whether the slash-separated path rel, or its base name,
+// matches one of globs.
+func matchAny(globs []string, rel string) bool {
+	for _, glob := range globs {
+		if ok, _ := path.Match(glob, rel); ok {
+			return true
+		}
+		if ok, _ := path.Match(glob, path.Base(rel)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// nameOf names a repository after its parent and its path relative to it,
+// or after its path relative to root, so repositories sharing a base name in
+// different directories keep apart. root itself is named after its base name.
+func (d *discovery) nameOf(repos []*found, repo *found) string {
+	if repo.name != "" {
+		return repo.name
+	}
+	repo.name = d.relative(repo.path)
+	if repo.name == "." || strings.HasPrefix(repo.name, "../") {
+		repo.name = filepath.Base(repo.path)
+	}
+	if repo.parent >= 0 {
+		parent := repos[repo.parent]
+		rel, err := filepath.Rel(parent.path, repo.path)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			rel = filepath.Base(repo.path)
+		}
+		repo.name = d.nameOf(repos, parent) + "/" + filepath.ToSlash(rel)
+	}
+	return repo.name
+}
+
+// gitDirs resolves a ".git" file to the repository's own Git directory and
+// the common directory it shares with other worktrees. For a plain ".git"
+// directory both are the same.
+func gitDirs(path string) (gitDir, commonDir string, err error) {
+	gitDir = filepath.Join(path, ".git")
+	info, err := os.Stat(gitDir)
+	if err != nil {
+		return "", "", err
+	}
+	if !info.IsDir() {
+		content, err := os.ReadFile(gitDir)
+		if err != nil {
+			return "", "", err
+		}
+		line := strings.TrimSpace(string(content))
+		if !strings.HasPrefix(line, "gitdir:") {
+			return "", "", fmt.Errorf("invalid .git file in '%s'", path)
+		}
+		gitDir = strings.TrimSpace(strings.TrimPrefix(line, "gitdir:"))
+		if !filepath.IsAbs(gitDir) {
+			gitDir = filepath.Join(path, gitDir)
+		}
+	}
+	commonDir = gitDir
+	if content, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		commonDir = strings.TrimSpace(string(content))
+		if !filepath.IsAbs(commonDir) {
+			commonDir = filepath.Join(gitDir, commonDir)
+		}
+	}
+	return filepath.Clean(gitDir), filepath.Clean(commonDir), nil
+}
+
+// openRepository opens the Git repository at path, including linked
+// worktrees whose objects live in the main repository.
+func openRepository(path string) (*git.Repository, error) {
+	return git.PlainOpenWithOptions(path, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
+}
+
+// within reports whether path is dir or inside it.
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// samePath reports whether a and b name the same directory.
+func samePath(a, b string) bool {
+	if resolved, err := filepath.EvalSymlinks(a); err == nil {
+		a = resolved
+	}
+	if resolved, err := filepath.EvalSymlinks(b); err == nil {
+		b = resolved
+	}
+	return filepath.Clean(a) == filepath.Clean(b)
+}
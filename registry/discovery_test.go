@@ -0,0 +1,221 @@
+// discovery_test.go
+package registry
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// addWorktree links path as a worktree of repo on branch, the way
+// "git worktree add" lays it out.
+func addWorktree(t *testing.T, repo *git.Repository, main, path, branch string) {
+	t.Helper()
+	head, _ := repo.Head()
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName(branch), head.Hash())); err != nil {
+		t.Fatal(err)
+	}
+	gitDir := filepath.Join(main, ".git", "worktrees", filepath.Base(path))
+	os.MkdirAll(gitDir, 0755)
+	os.MkdirAll(path, 0755)
+	os.WriteFile(filepath.Join(gitDir, "HEAD"), []byte("ref: refs/heads/"+branch+"\n"), 0644)
+	os.WriteFile(filepath.Join(gitDir, "commondir"), []byte("../..\n"), 0644)
+	os.WriteFile(filepath.Join(gitDir, "gitdir"), []byte(filepath.Join(path, ".git")+"\n"), 0644)
+	os.WriteFile(filepath.Join(path, ".git"), []byte("gitdir: "+gitDir+"\n"), 0644)
+}
+
+func TestDiscover(t *testing.T) {
+	projects := t.TempDir()
+	app := writeRepo(t, projects, "app", nil)
+	repo, _ := git.PlainOpen(app)
+	head := commitFile(t, repo, "README.md", "app")
+
+	writeRepo(t, filepath.Join(app, "vendor"), "lib", nil)
+	if _, err := git.PlainInit(filepath.Join(app, ".git", "modules", "sub"), true); err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(filepath.Join(app, "sub"), 0755)
+	os.WriteFile(filepath.Join(app, "sub", ".git"), []byte("gitdir: ../.git/modules/sub\n"), 0644)
+	os.WriteFile(filepath.Join(app, ".gitmodules"), []byte("[submodule \"sub\"]\n\tpath = sub\n\turl = ../sub\n"), 0644)
+	addWorktree(t, repo, app, filepath.Join(projects, "app-wt"), "wt")
+	os.MkdirAll(filepath.Join(projects, "notes"), 0755)
+
+	// Without Nested only declared submodules are found inside a repository.
+	discovered, err := Discover(projects, DiscoveryOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(discovered) != 3 || discovered[1].Name != "app/sub" || discovered[2].Name != "app/app-wt" {
+		t.Errorf("Expected app, app/sub and app/app-wt, got %+v", discovered)
+	}
+
+	discovered, err = Discover(projects, DiscoveryOptions{Nested: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []DiscoveredRepo{
+		{Name: "app", Path: app, Kind: KindRepository},
+		{Name: "app/sub", Path: filepath.Join(app, "sub"), Kind: KindSubmodule, Parent: "app"},
+		{Name: "app/vendor/lib", Path: filepath.Join(app, "vendor", "lib"), Kind: KindNested, Parent: "app"},
+		{Name: "app/app-wt", Path: filepath.Join(projects, "app-wt"), Kind: KindWorktree, Parent: "app"},
+	}
+	if len(discovered) != len(want) {
+		t.Fatalf("Expected %d repositories, got %+v", len(want), discovered)
+	}
+	for i := range want {
+		if discovered[i] != want[i] {
+			t.Errorf("Expected %+v, got %+v", want[i], discovered[i])
+		}
+	}
+
+	reg, err := NewRegistry(WithProjectsPath(projects), WithStatePath(""), WithNestedDiscovery(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	defer reg.Shutdown(ctx)
+
+	status, err := reg.RefreshStatus(ctx, "app/app-wt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Branch != "wt" || status.Head != head.String() {
+		t.Errorf("Expected the worktree's own status on 'wt', got %+v", status)
+	}
+	item, err := reg.Item("app/vendor/lib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Type != string(KindNested) || item.Parent != "app" {
+		t.Errorf("Expected a nested child of 'app', got %+v", item)
+	}
+	if len(reg.ListItems()) != 4 {
+		t.Errorf("Expected every repository to be registered once, got %d", len(reg.ListItems()))
+	}
+}
+
+func TestDiscoverOptions(t *testing.T) {
+	projects := t.TempDir()
+	writeRepo(t, projects, "api", nil)
+	writeRepo(t, projects, "web", nil)
+	writeRepo(t, filepath.Join(projects, "clients", "acme"), "portal", nil)
+	writeRepo(t, filepath.Join(projects, "node_modules"), "left-pad", nil)
+	writeRepo(t, filepath.Join(projects, "archive"), "old", nil)
+	writeRepo(t, filepath.Join(projects, "clients"), "scratch", nil)
+	os.WriteFile(filepath.Join(projects, IgnoreFile), []byte("# old work\narchive/\n"), 0644)
+	os.WriteFile(filepath.Join(projects, "clients", IgnoreFile), []byte("scratch\n"), 0644)
+	// A link back to the projects directory must not be walked forever.
+	if err := os.Symlink(projects, filepath.Join(projects, "clients", "loop")); err != nil {
+		t.Fatal(err)
+	}
+
+	names := func(opts DiscoveryOptions) []string {
+		t.Helper()
+		discovered, err := Discover(projects, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, repo := range discovered {
+			names = append(names, repo.Name)
+		}
+		return names
+	}
+	tests := []struct {
+		name string
+		opts DiscoveryOptions
+		want []string
+	}{
+		{"unlimited", DiscoveryOptions{Exclude: []string{"node_modules"}}, []string{"api", "clients/acme/portal", "web"}},
+		{"depth", DiscoveryOptions{MaxDepth: 2, Exclude: []string{"node_modules"}}, []string{"api", "web"}},
+		{"include", DiscoveryOptions{Include: []string{"clients/*/*", "web"}}, []string{"clients/acme/portal", "web"}},
+		{"no excludes", DiscoveryOptions{}, []string{"api", "clients/acme/portal", "node_modules/left-pad", "web"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := names(tt.opts)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	var last DiscoveryProgress
+	names(DiscoveryOptions{Progress: func(p DiscoveryProgress) { last = p }})
+	if !last.Done || last.Found != 4 || last.Dirs == 0 {
+		t.Errorf("Expected a final progress report of 4 repositories, got %+v", last)
+	}
+}
+
+func TestDiscoverSameNamedRepositories(t *testing.T) {
+	projects := t.TempDir()
+	writeRepo(t, filepath.Join(projects, "work"), "api", nil)
+	writeRepo(t, filepath.Join(projects, "personal"), "api", nil)
+
+	reg, err := NewRegistry(WithProjectsPath(projects), WithStatePath(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reg.RegistryActor.Mailbox.Close()
+	for _, name := range []string{"personal/api", "work/api"} {
+		item, err := reg.Item(name)
+		if err != nil {
+			t.Fatalf("Expected '%s' to be registered: %v", name, err)
+		}
+		if want := filepath.Join(projects, filepath.FromSlash(name)); item.Path != want {
+			t.Errorf("Expected '%s' at %s, got %s", name, want, item.Path)
+		}
+	}
+
+	// Rescanning adds neither again, and a name taken by another path is
+	// reported rather than dropped.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := reg.Scan(ctx, DiscoveryOptions{}); err != nil {
+		t.Fatalf("Expected a rescan to succeed, got %v", err)
+	}
+	if err := reg.RemoveRepo(ctx, "work/api"); err != nil {
+		t.Fatal(err)
+	}
+	elsewhere := writeRepo(t, t.TempDir(), "api", nil)
+	if _, err := Ask(ctx, reg.RegistryActor.Mailbox, AddRepo{Name: "work/api", Path: elsewhere}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reg.Scan(ctx, DiscoveryOptions{}); !errors.Is(err, ErrRepoExists) {
+		t.Errorf("Expected the taken name to be reported, got %v", err)
+	}
+	if item, _ := reg.Item("work/api"); item.Path != elsewhere {
+		t.Errorf("Expected 'work/api' to stay at %s, got %s", elsewhere, item.Path)
+	}
+}
+
+func TestDiscoverRootRepository(t *testing.T) {
+	projects := t.TempDir()
+	if _, err := git.PlainInit(projects, false); err != nil {
+		t.Fatal(err)
+	}
+	writeRepo(t, projects, "api", nil)
+	writeRepo(t, filepath.Join(projects, "clients"), "portal", nil)
+
+	discovered, err := Discover(projects, DiscoveryOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []DiscoveredRepo{
+		{Name: filepath.Base(projects), Path: projects, Kind: KindRepository},
+		{Name: "api", Path: filepath.Join(projects, "api"), Kind: KindRepository},
+		{Name: "clients/portal", Path: filepath.Join(projects, "clients", "portal"), Kind: KindRepository},
+	}
+	if !reflect.DeepEqual(discovered, want) {
+		t.Errorf("Expected %+v, got %+v", want, discovered)
+	}
+}
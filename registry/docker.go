@@ -3,15 +3,14 @@ package registry
 
 import (
     "context"
-    "encoding/json"
     "fmt"
-    "io"
     "os"
     "path/filepath"
+    "sort"
     "strings"
+    "time"
 
     "github.com/docker/docker/api/types"
-    "github.com/docker/docker/api/types/container"
     "github.com/docker/docker/api/types/filters"
 )
 
@@ -27,25 +26,32 @@ type DockerInfo struct {
     ImageID       string
     ImageTags     []string
     Containers    []types.Container
+    History       []BuiltImage // Images the registry built, newest first
+}
+
+// BuiltImage is an image the registry built for a repository.
+type BuiltImage struct {
+    ID      string
+    Created time.Time
+    Commit  string
+    Tags    []string // Tags given when it was built
+    Current []string // References still pointing at it
 }
 
 // GetDockerInfo retrieves Docker-related information for a repository.
 func (r *Registry) GetDockerInfo(repoName string) (*DockerInfo, error) {
-    repo, exists := r.RegistryActor.Repos[repoName]
-    if !exists {
-        return nil, fmt.Errorf("repository not found: %s", repoName)
+    repo, err := r.RegistryActor.lookup(repoName)
+    if err != nil {
+        return nil, err
     }
-
+    repo.mu.RLock()
     info := &DockerInfo{
         HasDockerfile: repo.IsDocker,
     }
-
-    if !repo.IsDocker {
-        return info, nil
-    }
+    imageName := repo.Manifest.ImageRepository(repoName) + ":latest"
+    repo.mu.RUnlock()
 
     // Get image information.
-    imageName := fmt.Sprintf("%s:latest", repo.Name)
     images, err := r.Docker.ImageList(context.Background(), types.ImageListOptions{
         Filters: filters.NewArgs(filters.Arg("reference", imageName)),
     })
@@ -54,6 +60,11 @@ func (r *Registry) GetDockerInfo(repoName string) (*DockerInfo, error) {
         info.ImageTags = images[0].RepoTags
     }
 
+    // Get every image built for the repository, tagged or not.
+    if history, err := r.builtImages(context.Background(), repoName); err == nil {
+        info.History = history
+    }
+
     // Get container information.
     containers, err := r.Docker.ContainerList(context.Background(), types.ContainerListOptions{
         All:     true,
@@ -66,48 +77,173 @@ func (r *Registry) GetDockerInfo(repoName string) (*DockerInfo, error) {
     return info, nil
 }
 
-// BuildImage builds a Docker image for a repository.
-func (r *Registry) BuildImage(repoName string) error {
-    repo, exists := r.RegistryActor.Repos[repoName]
-    if !exists {
-        return fmt.Errorf("repository not found: %s", repoName)
+// builtImages lists the images the registry built for a repository, newest
+// first.
+func (r *Registry) builtImages(ctx context.Context, repoName string) ([]BuiltImage, error) {
+    images, err := r.Docker.ImageList(ctx, types.ImageListOptions{
+        All:     true,
+        Filters: filters.NewArgs(filters.Arg("label", LabelRepo+"="+repoName)),
+    })
+    if err != nil {
+        return nil, fmt.Errorf("failed to list images of '%s': %w", repoName, err)
     }
-
-    if !repo.IsDocker {
-        return fmt.Errorf("repository does not have a Dockerfile: %s", repoName)
+    sort.Slice(images, func(i, j int) bool { return images[i].Created > images[j].Created })
+    history := make([]BuiltImage, 0, len(images))
+    for _, image := range images {
+        built := BuiltImage{
+            ID:      image.ID,
+            Created: time.Unix(image.Created, 0),
+            Commit:  image.Labels[LabelCommit],
+            Current: image.RepoTags,
+        }
+        if tags := image.Labels[LabelTags]; tags != "" {
+            built.Tags = strings.Split(tags, ",")
+        }
+        history = append(history, built)
     }
+    return history, nil
+}
 
-    ctx := context.Background()
-    buildContext := filepath.Join(repo.Path)
-
-    // Create build context tar.
-    tar, err := createBuildContext(buildContext)
+// BuildImage starts building the Docker image of a repository with the
+// named build profile from its manifest, or the default settings when
+// profile is empty, and returns the build. The image is tagged from the
+// repository's Git state with its manifest's tag templates and labelled
+// so GetDockerInfo can list it. Its events must be received, or
+// Wait called, for the build to make progress. The build is cancelled with
+// ctx or when the registry shuts down.
+func (r *Registry) BuildImage(ctx context.Context, repoName, profile string) (build *Build, err error) {
+    repo, err := r.RegistryActor.lookup(repoName)
+    if err != nil {
+        return nil, err
+    }
+    repo.mu.RLock()
+    path, manifest, manifestErr := repo.Path, repo.Manifest, repo.manifestErr
+    repo.mu.RUnlock()
+    if manifestErr != nil {
+        return nil, fmt.Errorf("failed to read build profiles: %w", manifestErr)
+    }
+    settings, err := manifest.Profile(profile)
+    if err != nil {
+        return nil, err
+    }
+    contextDir, options, err := buildOptions(path, settings)
+    if err != nil {
+        return nil, fmt.Errorf("repository '%s' cannot be built: %w", repoName, err)
+    }
+    askCtx, cancelAsk := context.WithTimeout(ctx, DefaultAskTimeout)
+    reply, err := Ask(askCtx, repo.Mailbox, ReadImageMetadata{})
+    cancelAsk()
     if err != nil {
-        return fmt.Errorf("failed to create build context: %w", err)
+        return nil, fmt.Errorf("failed to read git metadata of '%s': %w", repoName, err)
+    }
+    metadata := reply.(ImageMetadata)
+    tags, err := imageTags(metadata, manifest)
+    if err != nil {
+        return nil, err
+    }
+    options.Tags = tags
+    options.Labels[LabelRepo] = repoName
+    options.Labels[LabelCommit] = metadata.Commit
+    options.Labels[LabelTags] = strings.Join(tags, ",")
+
+    start := time.Now()
+    defer func() {
+        if err != nil {
+            r.Metrics.ObserveBuild(repoName, start, err)
+        }
+    }()
+
+    // Builds are cancelled when the registry shuts down.
+    ctx, cancel := withShutdown(ctx, r.ctx)
+
+    // Stream the build context tar.
+    buildContext, err := BuildContext(contextDir, options.Dockerfile)
+    if err != nil {
+        cancel()
+        return nil, fmt.Errorf("failed to create build context: %w", err)
     }
 
     // Build the image.
-    resp, err := r.Docker.ImageBuild(ctx, tar, types.ImageBuildOptions{
-        Tags:       []string{fmt.Sprintf("%s:latest", repo.Name)},
-        Dockerfile: "Dockerfile",
-    })
+    resp, err := r.Docker.ImageBuild(ctx, buildContext, options)
     if err != nil {
-        return fmt.Errorf("failed to build image: %w", err)
+        buildContext.Close()
+        cancel()
+        return nil, fmt.Errorf("failed to build image: %w", err)
     }
-    defer resp.Body.Close()
 
-    // Read the response.
-    _, err = io.Copy(os.Stdout, resp.Body)
+    build = newBuild(repoName)
+    build.Tags = tags
+    go func() {
+        defer cancel()
+        defer buildContext.Close()
+        defer resp.Body.Close()
+        r.Metrics.ObserveBuild(repoName, start, build.read(ctx, resp.Body))
+    }()
+    return build, nil
+}
+
+// BuildProfiles returns the names of the build profiles a repository's
+// manifest declares.
+func (r *Registry) BuildProfiles(repoName string) ([]string, error) {
+    repo, err := r.RegistryActor.lookup(repoName)
     if err != nil {
-        return fmt.Errorf("failed to read build response: %w", err)
+        return nil, err
+    }
+    repo.mu.RLock()
+    defer repo.mu.RUnlock()
+    if repo.manifestErr != nil {
+        return nil, fmt.Errorf("failed to read build profiles: %w", repo.manifestErr)
     }
+    return repo.Manifest.ProfileNames(), nil
+}
 
-    return nil
+// imageTags returns the references to tag an image built from metadata
+// with, using the manifest's tag templates.
+func imageTags(metadata ImageMetadata, manifest *Manifest) ([]string, error) {
+    tags, err := metadata.Tags(manifest.TagTemplates())
+    if err != nil {
+        return nil, err
+    }
+    if len(tags) == 0 {
+        return nil, fmt.Errorf("no tag templates apply to '%s'", metadata.Repo)
+    }
+    repository := manifest.ImageRepository(metadata.Repo)
+    for i, tag := range tags {
+        tags[i] = repository + ":" + tag
+    }
+    return tags, nil
 }
 
-// Utility functions.
-func createBuildContext(contextPath string) (io.Reader, error) {
-    // Implementation of tar creation.
-    // This would create a tar of the build context.
-    return nil, nil // Placeholder.
-}
\ No newline at end of file
+// buildOptions returns the context directory and Docker options for
+// building the repository at repoPath with settings.
+func buildOptions(repoPath string, settings BuildProfile) (string, types.ImageBuildOptions, error) {
+    dockerfile, err := settings.dockerfile()
+    if err != nil {
+        return "", types.ImageBuildOptions{}, err
+    }
+    contextDir := filepath.Join(repoPath, settings.Context)
+    if _, err := os.Stat(filepath.Join(contextDir, dockerfile)); err != nil {
+        return "", types.ImageBuildOptions{}, fmt.Errorf("failed to find Dockerfile: %w", err)
+    }
+
+    var args map[string]*string
+    if len(settings.Args) > 0 {
+        args = make(map[string]*string, len(settings.Args))
+        for name, value := range settings.Args {
+            value := value
+            args[name] = &value
+        }
+    }
+    labels := make(map[string]string, len(settings.Labels)+3)
+    for name, value := range settings.Labels {
+        labels[name] = value
+    }
+    return contextDir, types.ImageBuildOptions{
+        Dockerfile: dockerfile,
+        Target:     settings.Target,
+        BuildArgs:  args,
+        Labels:     labels,
+        CacheFrom:  settings.CacheFrom,
+        NoCache:    settings.NoCache,
+    }, nil
+}
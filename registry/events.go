@@ -0,0 +1,74 @@
+// File: registry/events.go
+package registry
+
+import (
+	"sync"
+	"time"
+)
+
+// EventType identifies what happened in the registry.
+type EventType string
+
+// Event types published by the registry.
+const (
+	EventActorCrashed   EventType = "actor.crashed"
+	EventActorRestarted EventType = "actor.restarted"
+	EventActorFailed    EventType = "actor.failed"
+	EventRepoAdded      EventType = "repo.added"   // The watcher found a new repository
+	EventRepoRemoved    EventType = "repo.removed" // The watcher saw a repository disappear
+	EventRepoChanged    EventType = "repo.changed" // The watcher saw a Dockerfile or pipeline change
+)
+
+// Event describes something that happened to a registry actor.
+type Event struct {
+	Type    EventType
+	Actor   string
+	Message string
+	Err     error
+	Time    time.Time
+}
+
+// EventBus fans registry events out to subscribers.
+type EventBus struct {
+	mu          sync.RWMutex
+	nextID      int
+	subscribers map[int]func(Event)
+}
+
+// NewEventBus initializes a new EventBus
+func NewEventBus() *EventBus {
+	return &EventBus{
+		subscribers: make(map[int]func(Event)),
+	}
+}
+
+// Subscribe registers fn to receive every published event and returns a
+// function that removes the subscription. Subscribers are called on the
+// publishing goroutine and must not block.
+func (b *EventBus) Subscribe(fn func(Event)) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := b.nextID
+	b.nextID++
+	b.subscribers[id] = fn
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers, id)
+	}
+}
+
+// Publish delivers an event to all subscribers.
+func (b *EventBus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, fn := range b.subscribers {
+		fn(e)
+	}
+}
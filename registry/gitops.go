@@ -0,0 +1,241 @@
+// File: registry/gitops.go
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// ErrNoUpstream is returned when pulling a branch that tracks no upstream,
+// or a detached HEAD.
+var ErrNoUpstream = errors.New("no upstream configured")
+
+// GitOperation is a Git command run across registered repositories.
+type GitOperation string
+
+const (
+	GitStatusOp GitOperation = "status"
+	GitFetchOp  GitOperation = "fetch"
+	GitPullOp   GitOperation = "pull"
+)
+
+// GitOutcome summarizes what a Git operation did to one repository.
+type GitOutcome string
+
+const (
+	OutcomeUpdated      GitOutcome = "updated"
+	OutcomeUpToDate     GitOutcome = "up-to-date"
+	OutcomeAhead        GitOutcome = "ahead"
+	OutcomeBehind       GitOutcome = "behind"
+	OutcomeDiverged     GitOutcome = "diverged"
+	OutcomeUnknown      GitOutcome = "unknown" // Too far from the upstream to compare
+	OutcomeDirty        GitOutcome = "dirty"
+	OutcomeDirtySkipped GitOutcome = "dirty-skipped"
+	OutcomeFailed       GitOutcome = "failed"
+)
+
+// GitResult is the outcome of a Git operation on one repository.
+type GitResult struct {
+	Repo     string
+	Outcome  GitOutcome
+	Status   GitStatus // Status after the operation
+	Err      error
+	Duration time.Duration
+}
+
+// GitOptions selects the repositories a bulk Git operation runs on.
+type GitOptions struct {
+	// Group limits the operation to repositories whose manifest lists it.
+	Group string
+	// Concurrency caps how many repositories are worked on at once.
+	Concurrency int
+}
+
+// RunGit is the message asking a RepoActor to run a Git operation. The reply
+// is a GitResult, together with its error when the operation failed.
+type RunGit struct {
+	Operation GitOperation
+}
+
+// Git runs op on every selected repository with a bounded worker pool and
+// returns one result per repository, sorted by name. Failures are reported
+// in the results rather than stopping the other repositories.
+func (r *Registry) Git(ctx context.Context, op GitOperation, opts GitOptions) ([]GitResult, error) {
+	switch op {
+	case GitStatusOp, GitFetchOp, GitPullOp:
+	default:
+		return nil, fmt.Errorf("unknown git operation '%s'", op)
+	}
+
+	names := r.RegistryActor.Names()
+	if opts.Group != "" {
+		names = r.Group(opts.Group)
+	}
+	sort.Strings(names)
+	if opts.Concurrency < 1 {
+		opts.Concurrency = DefaultConcurrency
+	}
+
+	results := make([]GitResult, len(names))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < opts.Concurrency && w < len(names); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = r.gitOne(ctx, names[i], op)
+			}
+		}()
+	}
+	for i := range names {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results, nil
+}
+
+// gitOne asks a single RepoActor to run op.
+func (r *Registry) gitOne(ctx context.Context, name string, op GitOperation) GitResult {
+	start := time.Now()
+	result := GitResult{Repo: name, Outcome: OutcomeFailed}
+	repo, err := r.RegistryActor.lookup(name)
+	if err == nil {
+		var reply interface{}
+		reply, err = Ask(ctx, repo.Mailbox, RunGit{Operation: op})
+		if value, ok := reply.(GitResult); ok {
+			result = value
+		}
+	}
+	if result.Err == nil {
+		result.Err = err
+	}
+	result.Duration = time.Since(start)
+	return result
+}
+
+// runGit runs a Git operation inside the RepoActor.
+func (r *RepoActor) runGit(ctx context.Context, op GitOperation) GitResult {
+	result := GitResult{Repo: r.Name}
+	fail := func(err error) GitResult {
+		result.Outcome = OutcomeFailed
+		result.Err = err
+		return result
+	}
+
+	status, err := r.refreshStatus()
+	if err != nil {
+		return fail(err)
+	}
+	r.mu.RLock()
+	repo := r.gitRepo
+	r.mu.RUnlock()
+
+	switch op {
+	case GitStatusOp:
+		result.Status = status
+		result.Outcome = statusOutcome(status)
+		return result
+
+	case GitFetchOp:
+		err := repo.FetchContext(ctx, &git.FetchOptions{RemoteName: remoteName(repo, status)})
+		switch {
+		case errors.Is(err, git.NoErrAlreadyUpToDate):
+			result.Outcome = OutcomeUpToDate
+		case err != nil:
+			return fail(fmt.Errorf("failed to fetch '%s': %w", r.Name, err))
+		default:
+			result.Outcome = OutcomeUpdated
+		}
+
+	case GitPullOp:
+		if status.Dirty {
+			result.Status = status
+			result.Outcome = OutcomeDirtySkipped
+			return result
+		}
+		worktree, err := repo.Worktree()
+		if err != nil {
+			return fail(fmt.Errorf("failed to open worktree of '%s': %w", r.Name, err))
+		}
+		remote, merge, err := upstreamOf(repo, status)
+		if err != nil {
+			return fail(fmt.Errorf("failed to pull '%s': %w", r.Name, err))
+		}
+		err = worktree.PullContext(ctx, &git.PullOptions{RemoteName: remote, ReferenceName: merge})
+		switch {
+		case errors.Is(err, git.NoErrAlreadyUpToDate):
+			result.Outcome = OutcomeUpToDate
+		case errors.Is(err, git.ErrNonFastForwardUpdate):
+			result.Outcome = OutcomeDiverged
+		case err != nil:
+			return fail(fmt.Errorf("failed to pull '%s': %w", r.Name, err))
+		default:
+			result.Outcome = OutcomeUpdated
+		}
+	}
+
+	result.Status, err = r.refreshStatus()
+	if err != nil {
+		return fail(err)
+	}
+	if result.Outcome == OutcomeUpToDate && result.Status.Ahead > 0 && result.Status.Behind > 0 {
+		result.Outcome = OutcomeDiverged
+	}
+	return result
+}
+
+// statusOutcome classifies a status relative to its upstream.
+func statusOutcome(status GitStatus) GitOutcome {
+	switch {
+	case status.Dirty:
+		return OutcomeDirty
+	case status.AheadBehindUnknown:
+		return OutcomeUnknown
+	case status.Ahead > 0 && status.Behind > 0:
+		return OutcomeDiverged
+	case status.Behind > 0:
+		return OutcomeBehind
+	case status.Ahead > 0:
+		return OutcomeAhead
+	}
+	return OutcomeUpToDate
+}
+
+// remoteName returns the remote the current branch tracks, or origin.
+func remoteName(repo *git.Repository, status GitStatus) string {
+	if cfg, err := repo.Config(); err == nil {
+		if branch, ok := cfg.Branches[status.Branch]; ok && branch.Remote != "" {
+			return branch.Remote
+		}
+	}
+	return git.DefaultRemoteName
+}
+
+// upstreamOf returns the remote and the remote branch the current branch
+// tracks, as set by its merge config.
+func upstreamOf(repo *git.Repository, status GitStatus) (string, plumbing.ReferenceName, error) {
+	if status.Branch == "" {
+		return "", "", fmt.Errorf("%w: HEAD is detached", ErrNoUpstream)
+	}
+	branch, err := repo.Branch(status.Branch)
+	if errors.Is(err, git.ErrBranchNotFound) || (err == nil && branch.Merge == "") {
+		return "", "", fmt.Errorf("%w for branch '%s'", ErrNoUpstream, status.Branch)
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read config of branch '%s': %w", status.Branch, err)
+	}
+	remote := branch.Remote
+	if remote == "" {
+		remote = git.DefaultRemoteName
+	}
+	return remote, branch.Merge, nil
+}
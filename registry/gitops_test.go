@@ -0,0 +1,195 @@
+// gitops_test.go
+package registry
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// cloneRepo clones url into dir/name.
+func cloneRepo(t *testing.T, url, dir, name string) *git.Repository {
+	t.Helper()
+	repo, err := git.PlainClone(filepath.Join(dir, name), false, &git.CloneOptions{URL: url})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+func outcomes(results []GitResult) map[string]GitOutcome {
+	byRepo := make(map[string]GitOutcome)
+	for _, result := range results {
+		byRepo[result.Repo] = result.Outcome
+	}
+	return byRepo
+}
+
+func TestRegistryGit(t *testing.T) {
+	// A bare remote with one commit, pushed from a scratch clone.
+	remote := filepath.Join(t.TempDir(), "remote.git")
+	if _, err := git.PlainInit(remote, true); err != nil {
+		t.Fatal(err)
+	}
+	scratch := t.TempDir()
+	upstream, err := git.PlainInit(scratch, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFile(t, upstream, "a.txt", "one")
+	if _, err := upstream.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{remote}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := upstream.Push(&git.PushOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	projects := t.TempDir()
+	cloneRepo(t, remote, projects, "behind")
+	cloneRepo(t, remote, projects, "current")
+	dirty := cloneRepo(t, remote, projects, "dirty")
+	diverged := cloneRepo(t, remote, projects, "diverged")
+	writeRepo(t, projects, "local", nil)
+
+	os.WriteFile(filepath.Join(projects, "dirty", "a.txt"), []byte("local change"), 0644)
+	commitFile(t, diverged, "b.txt", "local")
+
+	// Advance the remote after the clones were made.
+	commitFile(t, upstream, "a.txt", "two")
+	if err := upstream.Push(&git.PushOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	reg, err := NewRegistry(WithProjectsPath(projects), WithStatePath(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	defer reg.Shutdown(ctx)
+
+	if _, err := reg.Git(ctx, "gc", GitOptions{}); err == nil {
+		t.Error("Expected an unknown operation to fail")
+	}
+
+	results, err := reg.Git(ctx, GitFetchOp, GitOptions{Concurrency: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 5 || results[0].Repo != "behind" || results[4].Repo != "local" {
+		t.Fatalf("Expected one sorted result per repository, got %+v", results)
+	}
+	got := outcomes(results)
+	if got["behind"] != OutcomeUpdated || got["local"] != OutcomeFailed {
+		t.Errorf("Unexpected fetch outcomes %v", got)
+	}
+	if results[4].Err == nil {
+		t.Error("Expected the fetch error of 'local' to be collected")
+	}
+
+	results, _ = reg.Git(ctx, GitStatusOp, GitOptions{})
+	got = outcomes(results)
+	if got["behind"] != OutcomeBehind || got["dirty"] != OutcomeDirty || got["diverged"] != OutcomeDiverged {
+		t.Errorf("Unexpected status outcomes %v", got)
+	}
+
+	results, _ = reg.Git(ctx, GitPullOp, GitOptions{})
+	got = outcomes(results)
+	want := map[string]GitOutcome{
+		"behind":   OutcomeUpdated,
+		"current":  OutcomeUpdated,
+		"dirty":    OutcomeDirtySkipped,
+		"diverged": OutcomeDiverged,
+		"local":    OutcomeFailed,
+	}
+	for repo, outcome := range want {
+		if got[repo] != outcome {
+			t.Errorf("Expected pull of '%s' to be %s, got %s", repo, outcome, got[repo])
+		}
+	}
+
+	results, _ = reg.Git(ctx, GitPullOp, GitOptions{})
+	if got := outcomes(results); got["behind"] != OutcomeUpToDate {
+		t.Errorf("Expected a second pull to be up to date, got %v", got)
+	}
+	if head, _ := dirty.Head(); results[2].Status.Head != head.Hash().String() {
+		t.Errorf("Expected the skipped repository to keep its HEAD")
+	}
+}
+
+func TestRegistryGitPullTrackedBranch(t *testing.T) {
+	// A bare remote whose default branch is master, with a release branch.
+	remote := filepath.Join(t.TempDir(), "remote.git")
+	if _, err := git.PlainInit(remote, true); err != nil {
+		t.Fatal(err)
+	}
+	upstream, err := git.PlainInit(t.TempDir(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFile(t, upstream, "a.txt", "one")
+	if _, err := upstream.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{remote}}); err != nil {
+		t.Fatal(err)
+	}
+	worktree, _ := upstream.Worktree()
+	release := plumbing.NewBranchReferenceName("release")
+	if err := worktree.Checkout(&git.CheckoutOptions{Branch: release, Create: true}); err != nil {
+		t.Fatal(err)
+	}
+	commitFile(t, upstream, "b.txt", "release")
+	push := &git.PushOptions{RefSpecs: []config.RefSpec{"refs/heads/*:refs/heads/*"}}
+	if err := upstream.Push(push); err != nil {
+		t.Fatal(err)
+	}
+
+	projects := t.TempDir()
+	if _, err := git.PlainClone(filepath.Join(projects, "tracked"), false, &git.CloneOptions{URL: remote, ReferenceName: release}); err != nil {
+		t.Fatal(err)
+	}
+	detached := cloneRepo(t, remote, projects, "detached")
+	head, _ := detached.Head()
+	detachedTree, _ := detached.Worktree()
+	if err := detachedTree.Checkout(&git.CheckoutOptions{Hash: head.Hash()}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Advance both branches so pulling the wrong one cannot go unnoticed.
+	want := commitFile(t, upstream, "b.txt", "release two")
+	if err := worktree.Checkout(&git.CheckoutOptions{Branch: plumbing.Master}); err != nil {
+		t.Fatal(err)
+	}
+	commitFile(t, upstream, "a.txt", "master two")
+	if err := upstream.Push(push); err != nil {
+		t.Fatal(err)
+	}
+
+	reg, err := NewRegistry(WithProjectsPath(projects), WithStatePath(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	defer reg.Shutdown(ctx)
+
+	results, err := reg.Git(ctx, GitPullOp, GitOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected a result per repository, got %+v", results)
+	}
+	if result := results[0]; !errors.Is(result.Err, ErrNoUpstream) {
+		t.Errorf("Expected pulling a detached HEAD to fail with ErrNoUpstream, got %+v", result)
+	}
+	result := results[1]
+	if result.Outcome != OutcomeUpdated || result.Status.Branch != "release" || result.Status.Head != want.String() {
+		t.Errorf("Expected 'tracked' to be pulled from release to %s, got %+v", want, result)
+	}
+}
@@ -0,0 +1,266 @@
+// File: registry/gitstatus.go
+package registry
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// ErrNotGitRepo is reported for registered paths that are not Git repositories.
+var ErrNotGitRepo = errors.New("not a git repository")
+
+// maxAheadBehindWalk bounds how many commits are walked to count how far a
+// branch is ahead of or behind its upstream. A variable so tests can lower it.
+var maxAheadBehindWalk = 10000
+
+// GitStatus is a snapshot of a repository's working tree and branch.
+type GitStatus struct {
+	Branch     string // Empty when HEAD is detached
+	Head       string // Full hash of the HEAD commit, empty before the first commit
+	Dirty      bool   // Tracked files are modified or staged
+	Modified   int    // Tracked files that are modified or staged
+	Untracked  int
+	Upstream   string // Remote branch the current branch tracks, if any
+	Ahead      int
+	Behind     int
+	LastCommit time.Time
+	CheckedAt  time.Time
+
+	// AheadBehindUnknown is set when the branch and its upstream are too far
+	// apart to count Ahead and Behind, which are left at zero.
+	AheadBehindUnknown bool
+}
+
+// ShortHead returns the abbreviated HEAD hash.
+func (s GitStatus) ShortHead() string {
+	if len(s.Head) > 7 {
+		return s.Head[:7]
+	}
+	return s.Head
+}
+
+// Summary describes the status in one line, e.g. "main@1a2b3c4 dirty ↑1".
+func (s GitStatus) Summary() string {
+	ref := s.Branch
+	if ref == "" {
+		ref = "(detached)"
+	}
+	summary := ref
+	if s.Head != "" {
+		summary += "@" + s.ShortHead()
+	}
+	if s.Dirty {
+		summary += " dirty"
+	} else {
+		summary += " clean"
+	}
+	if s.Untracked > 0 {
+		summary += fmt.Sprintf(" ?%d", s.Untracked)
+	}
+	if s.Ahead > 0 {
+		summary += fmt.Sprintf(" ↑%d", s.Ahead)
+	}
+	if s.Behind > 0 {
+		summary += fmt.Sprintf(" ↓%d", s.Behind)
+	}
+	if s.AheadBehindUnknown {
+		summary += " ↑?↓?"
+	}
+	return summary
+}
+
+// ReadGitStatus inspects repo's HEAD, working tree and upstream branch.
+func ReadGitStatus(repo *git.Repository) (GitStatus, error) {
+	status := GitStatus{CheckedAt: time.Now()}
+
+	head, err := repo.Head()
+	switch {
+	case errors.Is(err, plumbing.ErrReferenceNotFound):
+		// No commits yet: report the branch HEAD points at.
+		if ref, err := repo.Reference(plumbing.HEAD, false); err == nil {
+			status.Branch = ref.Target().Short()
+		}
+	case err != nil:
+		return status, fmt.Errorf("failed to read HEAD: %w", err)
+	default:
+		status.Head = head.Hash().String()
+		if head.Name().IsBranch() {
+			status.Branch = head.Name().Short()
+		}
+		commit, err := repo.CommitObject(head.Hash())
+		if err != nil {
+			return status, fmt.Errorf("failed to read HEAD commit: %w", err)
+		}
+		status.LastCommit = commit.Committer.When
+	}
+
+	if worktree, err := repo.Worktree(); err == nil {
+		files, err := worktree.Status()
+		if err != nil {
+			return status, fmt.Errorf("failed to read worktree status: %w", err)
+		}
+		for _, file := range files {
+			if file.Worktree == git.Untracked {
+				status.Untracked++
+				continue
+			}
+			if file.Staging != git.Unmodified || file.Worktree != git.Unmodified {
+				status.Modified++
+			}
+		}
+		status.Dirty = status.Modified > 0
+	}
+
+	if status.Branch != "" && status.Head != "" {
+		if err := readUpstream(repo, &status); err != nil {
+			return status, err
+		}
+	}
+	return status, nil
+}
+
+// readUpstream fills in the upstream branch and how far HEAD is ahead of and
+// behind it.
+func readUpstream(repo *git.Repository, status *GitStatus) error {
+	cfg, err := repo.Config()
+	if err != nil {
+		return fmt.Errorf("failed to read git config: %w", err)
+	}
+	branch, ok := cfg.Branches[status.Branch]
+	if !ok || branch.Remote == "" || branch.Merge == "" {
+		return nil
+	}
+	upstreamName := plumbing.NewRemoteReferenceName(branch.Remote, branch.Merge.Short())
+	status.Upstream = upstreamName.Short()
+	upstream, err := repo.Reference(upstreamName, true)
+	if err != nil {
+		// Not fetched yet; nothing to compare against.
+		return nil
+	}
+
+	ahead, behind, counted, err := aheadBehind(repo, plumbing.NewHash(status.Head), upstream.Hash())
+	if err != nil {
+		return err
+	}
+	status.Ahead, status.Behind, status.AheadBehindUnknown = ahead, behind, !counted
+	return nil
+}
+
+// Sides of an ahead/behind walk a commit is reachable from.
+const (
+	fromOurs uint8 = 1 << iota
+	fromTheirs
+	fromBoth = fromOurs | fromTheirs
+)
+
+// aheadBehind counts the commits reachable from ours but not theirs, and
+// from theirs but not ours. Both histories are walked together, newest
+// commit first, until only commits reachable from both are left, so the
+// walk ends at their merge base. Commits reachable from both keep being
+// walked while they are as old as a commit counted for one side only, which
+// commits made in the same second can be. It reports false, with no
+// counts, when maxAheadBehindWalk commits were walked before that. History
+// ends early in shallow clones.
+func aheadBehind(repo *git.Repository, ours, theirs plumbing.Hash) (int, int, bool, error) {
+	if ours == theirs {
+		return 0, 0, true, nil
+	}
+	w := &aheadBehindWalk{flags: make(map[plumbing.Hash]uint8), queued: make(map[plumbing.Hash]bool)}
+	for _, tip := range []struct {
+		hash plumbing.Hash
+		side uint8
+	}{{ours, fromOurs}, {theirs, fromTheirs}} {
+		commit, err := repo.CommitObject(tip.hash)
+		if err != nil {
+			return 0, 0, false, fmt.Errorf("failed to read commit %s: %w", tip.hash, err)
+		}
+		w.paint(commit, tip.side)
+	}
+
+	for walked := 0; w.active > 0 || (w.queue.Len() > 0 && !w.queue[0].Committer.When.Before(w.oldest)); walked++ {
+		if walked >= maxAheadBehindWalk {
+			return 0, 0, false, nil
+		}
+		commit := heap.Pop(&w.queue).(*object.Commit)
+		delete(w.queued, commit.Hash)
+		side := w.flags[commit.Hash]
+		if side != fromBoth {
+			w.active--
+			if w.oldest.IsZero() || commit.Committer.When.Before(w.oldest) {
+				w.oldest = commit.Committer.When
+			}
+		}
+		for _, hash := range commit.ParentHashes {
+			parent, err := repo.CommitObject(hash)
+			if errors.Is(err, plumbing.ErrObjectNotFound) {
+				continue
+			}
+			if err != nil {
+				return 0, 0, false, fmt.Errorf("failed to read commit %s: %w", hash, err)
+			}
+			w.paint(parent, side)
+		}
+	}
+
+	ahead, behind := 0, 0
+	for _, side := range w.flags {
+		switch side {
+		case fromOurs:
+			ahead++
+		case fromTheirs:
+			behind++
+		}
+	}
+	return ahead, behind, true, nil
+}
+
+// aheadBehindWalk is the state of aheadBehind.
+type aheadBehindWalk struct {
+	flags  map[plumbing.Hash]uint8 // Sides each commit seen is reachable from
+	queued map[plumbing.Hash]bool
+	queue  commitQueue
+	active int       // Queued commits reachable from one side only
+	oldest time.Time // Oldest commit walked as reachable from one side only
+}
+
+// paint marks commit as reachable from side, queueing it to pass that on to
+// its parents unless it already was.
+func (w *aheadBehindWalk) paint(commit *object.Commit, side uint8) {
+	old := w.flags[commit.Hash]
+	painted := old | side
+	if painted == old {
+		return
+	}
+	w.flags[commit.Hash] = painted
+	if w.queued[commit.Hash] {
+		if painted == fromBoth {
+			w.active--
+		}
+		return
+	}
+	w.queued[commit.Hash] = true
+	heap.Push(&w.queue, commit)
+	if painted != fromBoth {
+		w.active++
+	}
+}
+
+// commitQueue is a heap of commits, newest first.
+type commitQueue []*object.Commit
+
+func (q commitQueue) Len() int           { return len(q) }
+func (q commitQueue) Less(i, j int) bool { return q[i].Committer.When.After(q[j].Committer.When) }
+func (q commitQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *commitQueue) Push(x any)        { *q = append(*q, x.(*object.Commit)) }
+func (q *commitQueue) Pop() any {
+	old := *q
+	commit := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return commit
+}
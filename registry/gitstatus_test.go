@@ -0,0 +1,189 @@
+// gitstatus_test.go
+package registry
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// commitFile writes name into the worktree and commits it.
+func commitFile(t *testing.T, repo *git.Repository, name, content string) plumbing.Hash {
+	t.Helper()
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(worktree.Filesystem.Root(), name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := worktree.Add(name); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := worktree.Commit("update "+name, &git.CommitOptions{
+		Author: &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func TestReadGitStatus(t *testing.T) {
+	path := t.TempDir()
+	repo, err := git.PlainInit(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := ReadGitStatus(repo)
+	if err != nil {
+		t.Fatalf("Status of an empty repository failed: %v", err)
+	}
+	if status.Branch != "master" || status.Head != "" {
+		t.Errorf("Unexpected status of an empty repository: %+v", status)
+	}
+
+	base := commitFile(t, repo, "a.txt", "one")
+	commitFile(t, repo, "a.txt", "two")
+	head := commitFile(t, repo, "a.txt", "three")
+
+	// Track a remote branch two commits behind HEAD.
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewRemoteReferenceName("origin", "master"), base)); err != nil {
+		t.Fatal(err)
+	}
+	cfg, _ := repo.Config()
+	cfg.Remotes["origin"] = &config.RemoteConfig{Name: "origin", URLs: []string{"https://example.com/repo.git"}}
+	cfg.Branches["master"] = &config.Branch{Name: "master", Remote: "origin", Merge: plumbing.NewBranchReferenceName("master")}
+	if err := repo.SetConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	os.WriteFile(filepath.Join(path, "a.txt"), []byte("dirty"), 0644)
+	os.WriteFile(filepath.Join(path, "new.txt"), []byte("new"), 0644)
+
+	status, err = ReadGitStatus(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Head != head.String() || !status.Dirty || status.Untracked != 1 {
+		t.Errorf("Unexpected worktree status: %+v", status)
+	}
+	if status.Upstream != "origin/master" || status.Ahead != 2 || status.Behind != 0 {
+		t.Errorf("Expected to be 2 ahead of origin/master, got %+v", status)
+	}
+	if status.LastCommit.IsZero() {
+		t.Error("Expected the last commit time to be set")
+	}
+}
+
+func TestRegistryRefreshStatus(t *testing.T) {
+	projects := t.TempDir()
+	path := writeRepo(t, projects, "demo", nil)
+
+	reg, err := NewRegistry(WithProjectsPath(projects), WithStatePath(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	defer reg.Shutdown(ctx)
+
+	repo, _ := git.PlainOpen(path)
+	head := commitFile(t, repo, "README.md", "hello")
+
+	status, err := reg.RefreshStatus(ctx, "demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Head != head.String() || status.Dirty {
+		t.Errorf("Unexpected status %+v", status)
+	}
+
+	items := reg.ListItems()
+	if len(items) != 1 || items[0].GitRepo == nil || items[0].GitStatus == nil || items[0].GitStatus.Head != head.String() {
+		t.Errorf("Expected the list item to carry the git repository and status, got %+v", items)
+	}
+
+	if _, err := reg.AddRepo(ctx, "plain", t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	if errs := reg.RefreshAllStatus(ctx); len(errs) != 1 || errs["plain"] == nil {
+		t.Errorf("Expected only 'plain' to fail, got %v", errs)
+	}
+}
+
+func TestAheadBehind(t *testing.T) {
+	repo, err := git.PlainInit(t.TempDir(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	worktree, _ := repo.Worktree()
+	when, step := time.Now().Add(-time.Hour), time.Minute
+	commit := func(content string) plumbing.Hash {
+		t.Helper()
+		os.WriteFile(filepath.Join(worktree.Filesystem.Root(), "a.txt"), []byte(content), 0644)
+		worktree.Add("a.txt")
+		when = when.Add(step)
+		hash, err := worktree.Commit(content, &git.CommitOptions{
+			Author: &object.Signature{Name: "Test", Email: "test@example.com", When: when},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hash
+	}
+	var history, fork, burst []plumbing.Hash
+	for i := 0; i < 20; i++ {
+		history = append(history, commit(fmt.Sprintf("main %d", i)))
+	}
+	// Commits made within the same second.
+	step = 0
+	for i := 0; i < 5; i++ {
+		burst = append(burst, commit(fmt.Sprintf("burst %d", i)))
+	}
+	step = time.Minute
+	if err := worktree.Checkout(&git.CheckoutOptions{Hash: history[9], Branch: plumbing.NewBranchReferenceName("fork"), Create: true}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		fork = append(fork, commit(fmt.Sprintf("fork %d", i)))
+	}
+
+	defer func(limit int) { maxAheadBehindWalk = limit }(maxAheadBehindWalk)
+	tests := []struct {
+		name          string
+		limit         int
+		ours, theirs  plumbing.Hash
+		ahead, behind int
+		counted       bool
+	}{
+		{"ahead of a history longer than the limit", 5, history[19], history[18], 1, 0, true},
+		{"behind a history longer than the limit", 5, history[18], history[19], 0, 1, true},
+		{"same commit", 5, history[19], history[19], 0, 0, true},
+		{"commits in the same second", 10, burst[4], burst[2], 2, 0, true},
+		{"commits in the same second behind", 20, burst[1], burst[4], 0, 3, true},
+		{"diverged", 100, fork[2], burst[4], 3, 15, true},
+		{"diverged past the limit", 5, fork[2], burst[4], 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			maxAheadBehindWalk = tt.limit
+			ahead, behind, counted, err := aheadBehind(repo, tt.ours, tt.theirs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ahead != tt.ahead || behind != tt.behind || counted != tt.counted {
+				t.Errorf("Expected %d ahead, %d behind, counted %v; got %d, %d, %v", tt.ahead, tt.behind, tt.counted, ahead, behind, counted)
+			}
+		})
+	}
+}
@@ -0,0 +1,295 @@
+// File: registry/health.go
+package registry
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// Health checks scored for every repository.
+const (
+	CheckReadme      = "readme"
+	CheckLicense     = "license"
+	CheckTests       = "tests"
+	CheckCI          = "ci"
+	CheckDockerfile  = "dockerfile"
+	CheckGitignore   = "gitignore"
+	CheckActivity    = "recent-activity"
+	CheckBranches    = "stale-branches"
+	CheckUncommitted = "uncommitted-work"
+)
+
+// HealthChecks lists the checks in the order they are reported.
+var HealthChecks = []string{
+	CheckReadme,
+	CheckLicense,
+	CheckTests,
+	CheckCI,
+	CheckDockerfile,
+	CheckGitignore,
+	CheckActivity,
+	CheckBranches,
+	CheckUncommitted,
+}
+
+const (
+	// ActivityWindow is how recent the last commit must be to count as active.
+	ActivityWindow = 90 * 24 * time.Hour
+	// StaleBranchAge is how old a branch's last commit may be before the
+	// branch counts as stale.
+	StaleBranchAge = 180 * 24 * time.Hour
+	// maxTestScan bounds how many files are inspected looking for tests.
+	maxTestScan = 5000
+)
+
+// HealthCheck is the result of one checklist item.
+type HealthCheck struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// RepoHealth scores one repository against the checklist.
+type RepoHealth struct {
+	Repo   string        `json:"repo"`
+	Score  int           `json:"score"` // Percentage of checks passed
+	Checks []HealthCheck `json:"checks"`
+	Error  string        `json:"error,omitempty"`
+}
+
+// Failed returns the names of the checks the repository did not pass.
+func (h RepoHealth) Failed() []string {
+	var failed []string
+	for _, check := range h.Checks {
+		if !check.Passed {
+			failed = append(failed, check.Name)
+		}
+	}
+	return failed
+}
+
+// HealthReport is the health of every repository and the registry overall.
+type HealthReport struct {
+	CheckedAt time.Time      `json:"checked_at"`
+	Score     int            `json:"score"`   // Average repository score
+	Passing   map[string]int `json:"passing"` // Repositories passing each check
+	Repos     []RepoHealth   `json:"repos"`
+}
+
+// CheckHealth asks a RepoActor to score itself. The reply is a RepoHealth.
+type CheckHealth struct{}
+
+// Health scores every repository in parallel and aggregates the results.
+// Repositories are sorted by name.
+func (r *Registry) Health(ctx context.Context) HealthReport {
+	names := r.RegistryActor.Names()
+	sort.Strings(names)
+
+	repos := make([]RepoHealth, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			repos[i] = RepoHealth{Repo: name}
+			repo, err := r.RegistryActor.lookup(name)
+			if err != nil {
+				repos[i].Error = err.Error()
+				return
+			}
+			result, err := Ask(ctx, repo.Mailbox, CheckHealth{})
+			if err != nil {
+				repos[i].Error = err.Error()
+				return
+			}
+			repos[i] = result.(RepoHealth)
+		}(i, name)
+	}
+	wg.Wait()
+
+	report := HealthReport{CheckedAt: time.Now(), Passing: make(map[string]int), Repos: repos}
+	for _, name := range HealthChecks {
+		report.Passing[name] = 0
+	}
+	total := 0
+	for _, repo := range repos {
+		total += repo.Score
+		for _, check := range repo.Checks {
+			if check.Passed {
+				report.Passing[check.Name]++
+			}
+		}
+	}
+	if len(repos) > 0 {
+		report.Score = total / len(repos)
+	}
+	return report
+}
+
+// checkHealth runs the checklist against the repository.
+func (r *RepoActor) checkHealth(now time.Time) RepoHealth {
+	r.mu.RLock()
+	isDocker, hasPipeline, repo := r.IsDocker, r.HasPipeline, r.gitRepo
+	r.mu.RUnlock()
+
+	health := RepoHealth{Repo: r.Name}
+	add := func(name string, passed bool, detail string) {
+		health.Checks = append(health.Checks, HealthCheck{Name: name, Passed: passed, Detail: detail})
+	}
+
+	readme := findFile(r.Path, "README")
+	add(CheckReadme, readme != "", readme)
+	license := findFile(r.Path, "LICENSE", "LICENCE", "COPYING")
+	add(CheckLicense, license != "", license)
+	tests := findTest(r.Path)
+	add(CheckTests, tests != "", tests)
+	add(CheckCI, hasPipeline, "")
+	add(CheckDockerfile, isDocker, "")
+	_, err := os.Stat(filepath.Join(r.Path, ".gitignore"))
+	add(CheckGitignore, err == nil, "")
+
+	if repo == nil {
+		add(CheckActivity, false, ErrNotGitRepo.Error())
+		add(CheckBranches, false, ErrNotGitRepo.Error())
+		add(CheckUncommitted, false, ErrNotGitRepo.Error())
+	} else {
+		status, statusErr := r.refreshStatus()
+		switch {
+		case statusErr != nil:
+			add(CheckActivity, false, statusErr.Error())
+		case status.LastCommit.IsZero():
+			add(CheckActivity, false, "no commits")
+		default:
+			age := now.Sub(status.LastCommit)
+			add(CheckActivity, age <= ActivityWindow, "last commit "+status.LastCommit.Format("2006-01-02"))
+		}
+
+		stale, err := staleBranches(repo, now.Add(-StaleBranchAge))
+		switch {
+		case err != nil:
+			add(CheckBranches, false, err.Error())
+		case len(stale) > 0:
+			add(CheckBranches, false, strings.Join(stale, ", "))
+		default:
+			add(CheckBranches, true, "")
+		}
+
+		switch {
+		case statusErr != nil:
+			add(CheckUncommitted, false, statusErr.Error())
+		case status.Modified > 0 || status.Untracked > 0:
+			add(CheckUncommitted, false, fmt.Sprintf("%d modified, %d untracked files", status.Modified, status.Untracked))
+		default:
+			add(CheckUncommitted, true, "")
+		}
+	}
+
+	passed := len(health.Checks) - len(health.Failed())
+	health.Score = passed * 100 / len(health.Checks)
+	return health
+}
+
+// findFile returns the first file in dir whose name, ignoring case and
+// extension, is one of names.
+func findFile(dir string, names ...string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		base := strings.ToUpper(strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())))
+		for _, name := range names {
+			if base == name {
+				return entry.Name()
+			}
+		}
+	}
+	return ""
+}
+
+// findTest returns the path, relative to dir, of the first test file or test
+// directory found.
+func findTest(dir string) string {
+	found := ""
+	scanned := 0
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		name := d.Name()
+		if d.IsDir() {
+			switch name {
+			case ".git", "node_modules", "vendor":
+				return filepath.SkipDir
+			case "test", "tests", "__tests__", "spec":
+				if path != dir {
+					found, _ = filepath.Rel(dir, path)
+					return filepath.SkipAll
+				}
+			}
+			return nil
+		}
+		if scanned++; scanned > maxTestScan {
+			return filepath.SkipAll
+		}
+		if isTestFile(name) {
+			found, _ = filepath.Rel(dir, path)
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return found
+}
+
+// isTestFile recognizes common test file naming conventions.
+func isTestFile(name string) bool {
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	switch {
+	case strings.HasSuffix(base, "_test"):
+		return true
+	case strings.HasSuffix(base, ".test"), strings.HasSuffix(base, ".spec"):
+		return true
+	case ext == ".py" && strings.HasPrefix(base, "test_"):
+		return true
+	}
+	return false
+}
+
+// staleBranches returns the local branches whose last commit is older than
+// cutoff, sorted by name.
+func staleBranches(repo *git.Repository, cutoff time.Time) ([]string, error) {
+	branches, err := repo.Branches()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list branches: %w", err)
+	}
+	var stale []string
+	err = branches.ForEach(func(ref *plumbing.Reference) error {
+		commit, err := repo.CommitObject(ref.Hash())
+		if err != nil {
+			return nil
+		}
+		if commit.Committer.When.Before(cutoff) {
+			stale = append(stale, ref.Name().Short())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read branches: %w", err)
+	}
+	sort.Strings(stale)
+	return stale, nil
+}
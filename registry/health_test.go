@@ -0,0 +1,76 @@
+// health_test.go
+package registry
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestRegistryHealth(t *testing.T) {
+	projects := t.TempDir()
+	path := writeRepo(t, projects, "good", map[string]string{
+		"README.md":    "# good\n",
+		"LICENSE":      "MIT\n",
+		".gitignore":   "bin/\n",
+		"Dockerfile":   "FROM scratch\n",
+		"main_test.go": "package main\n",
+	})
+	os.MkdirAll(filepath.Join(path, ".github", "workflows"), 0755)
+	os.WriteFile(filepath.Join(path, ".github", "workflows", "ci.yml"), []byte("on: push\n"), 0644)
+	repo, _ := git.PlainOpen(path)
+	worktree, _ := repo.Worktree()
+	worktree.AddGlob(".")
+	if _, err := worktree.Commit("initial", &git.CommitOptions{
+		Author: &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(filepath.Join(projects, "plain"), 0755)
+
+	reg, err := NewRegistry(WithProjectsPath(projects), WithStatePath(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	defer reg.Shutdown(ctx)
+	if _, err := reg.AddRepo(ctx, "plain", filepath.Join(projects, "plain")); err != nil {
+		t.Fatal(err)
+	}
+
+	report := reg.Health(ctx)
+	if len(report.Repos) != 2 || report.Repos[0].Repo != "good" {
+		t.Fatalf("Expected a sorted report for 2 repositories, got %+v", report.Repos)
+	}
+	good := report.Repos[0]
+	if good.Score != 100 || len(good.Checks) != len(HealthChecks) {
+		t.Errorf("Expected 'good' to pass every check, failed %v", good.Failed())
+	}
+	if plain := report.Repos[1]; plain.Score != 0 {
+		t.Errorf("Expected 'plain' to fail every check, got %d (%+v)", plain.Score, plain.Checks)
+	}
+	if report.Score != 50 || report.Passing[CheckReadme] != 1 {
+		t.Errorf("Unexpected aggregate score %d, passing %v", report.Score, report.Passing)
+	}
+
+	// Half a year later the repository is inactive and master is stale.
+	actor, _ := reg.RegistryActor.lookup("good")
+	os.WriteFile(filepath.Join(path, "README.md"), []byte("changed\n"), 0644)
+	later := actor.checkHealth(time.Now().Add(StaleBranchAge + time.Hour))
+	failed := later.Failed()
+	want := []string{CheckActivity, CheckBranches, CheckUncommitted}
+	if len(failed) != len(want) {
+		t.Fatalf("Expected %v to fail, got %v", want, failed)
+	}
+	for i := range want {
+		if failed[i] != want[i] {
+			t.Errorf("Expected %v to fail, got %v", want, failed)
+		}
+	}
+}
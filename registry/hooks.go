@@ -0,0 +1,290 @@
+// File: registry/hooks.go
+package registry
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// HookVersion is the version of the embedded hook templates. Bump it when a
+// template changes so installed copies are reported as outdated.
+const HookVersion = 1
+
+// hookMarker starts the line identifying a hook managed by the registry.
+const hookMarker = "# managed-by: go-middleware-registry"
+
+//go:embed hooks/*
+var hookTemplates embed.FS
+
+// ManagedHooks lists the hooks the registry installs.
+var ManagedHooks = []string{"pre-commit", "commit-msg", "pre-push"}
+
+// HookState describes an installed hook relative to its template.
+type HookState string
+
+const (
+	HookMissing   HookState = "missing"   // No hook is installed
+	HookCurrent   HookState = "current"   // The current template is installed
+	HookOutdated  HookState = "outdated"  // An older, unmodified template is installed
+	HookModified  HookState = "modified"  // A managed hook was edited by hand
+	HookUnmanaged HookState = "unmanaged" // The hook was not installed by the registry
+)
+
+// HookAction is an operation on a repository's managed hooks.
+type HookAction string
+
+const (
+	HookInstall HookAction = "install" // Install missing hooks and update outdated ones
+	HookUpdate  HookAction = "update"  // Update outdated hooks only
+	HookRemove  HookAction = "remove"  // Remove managed hooks
+)
+
+// HookStatus is the state of one hook in a repository.
+type HookStatus struct {
+	Name    string
+	State   HookState
+	Version int  // Template version of a managed hook
+	Changed bool // The last action wrote or removed the hook
+	Skipped bool // The action left a modified or unmanaged hook alone
+}
+
+// ManageHooks asks a RepoActor to apply a hook action. The reply is a
+// []HookStatus. Force overwrites or removes modified and unmanaged hooks.
+type ManageHooks struct {
+	Action HookAction
+	Force  bool
+}
+
+// HookOptions selects the repositories a hook action applies to.
+type HookOptions struct {
+	Repos []string // Repository names; every repository when empty
+	Group string   // Only repositories in this manifest group
+	Force bool
+}
+
+// HookResult is the outcome of a hook action on one repository.
+type HookResult struct {
+	Repo  string
+	Hooks []HookStatus
+	Err   error
+}
+
+// Hooks applies action to the selected repositories in parallel and returns
+// one result per repository, sorted by name.
+func (r *Registry) Hooks(ctx context.Context, action HookAction, opts HookOptions) ([]HookResult, error) {
+	switch action {
+	case HookInstall, HookUpdate, HookRemove:
+	default:
+		return nil, fmt.Errorf("unknown hook action '%s'", action)
+	}
+
+	names := r.selectRepos(opts.Repos, opts.Group)
+
+	results := make([]HookResult, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			results[i] = HookResult{Repo: name}
+			repo, err := r.RegistryActor.lookup(name)
+			if err != nil {
+				results[i].Err = err
+				return
+			}
+			hooks, err := Ask(ctx, repo.Mailbox, ManageHooks{Action: action, Force: opts.Force})
+			if err != nil {
+				results[i].Err = err
+				return
+			}
+			results[i].Hooks = hooks.([]HookStatus)
+		}(i, name)
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// hooksDir returns the directory Git runs the repository's hooks from,
+// honoring core.hooksPath.
+func (r *RepoActor) hooksDir() (string, error) {
+	r.mu.RLock()
+	repo := r.gitRepo
+	r.mu.RUnlock()
+	if repo == nil {
+		return "", fmt.Errorf("%w: %s", ErrNotGitRepo, r.Path)
+	}
+	if cfg, err := repo.Config(); err == nil {
+		if dir := cfg.Raw.Section("core").Option("hooksPath"); dir != "" {
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(r.Path, dir)
+			}
+			return dir, nil
+		}
+	}
+	// Worktrees share the hooks of their main repository.
+	_, commonDir, err := gitDirs(r.Path)
+	if err != nil {
+		return "", fmt.Errorf("failed to find the git directory of '%s': %w", r.Name, err)
+	}
+	return filepath.Join(commonDir, "hooks"), nil
+}
+
+// hookStatuses reports the state of every managed hook.
+func (r *RepoActor) hookStatuses() ([]HookStatus, error) {
+	dir, err := r.hooksDir()
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]HookStatus, 0, len(ManagedHooks))
+	for _, name := range ManagedHooks {
+		status, err := readHook(dir, name)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// manageHooks applies action to every managed hook.
+func (r *RepoActor) manageHooks(action HookAction, force bool) ([]HookStatus, error) {
+	dir, err := r.hooksDir()
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]HookStatus, 0, len(ManagedHooks))
+	for _, name := range ManagedHooks {
+		status, err := readHook(dir, name)
+		if err != nil {
+			return statuses, err
+		}
+
+		owned := status.State == HookCurrent || status.State == HookOutdated
+		foreign := status.State == HookModified || status.State == HookUnmanaged
+		switch {
+		case foreign && !force:
+			status.Skipped = true
+		case action == HookRemove && (owned || foreign):
+			if err := os.Remove(filepath.Join(dir, name)); err != nil {
+				return statuses, fmt.Errorf("failed to remove hook '%s' from '%s': %w", name, r.Name, err)
+			}
+			status = HookStatus{Name: name, State: HookMissing, Changed: true}
+		case action == HookRemove, status.State == HookCurrent:
+		case action == HookUpdate && status.State == HookMissing:
+		default:
+			if err := writeHook(dir, name); err != nil {
+				return statuses, fmt.Errorf("failed to install hook '%s' in '%s': %w", name, r.Name, err)
+			}
+			status = HookStatus{Name: name, State: HookCurrent, Version: HookVersion, Changed: true}
+		}
+		statuses = append(statuses, status)
+	}
+	fmt.Printf("Hooks %s for repo '%s'\n", action, r.Name)
+	return statuses, nil
+}
+
+// renderHook returns the installed form of a template: its shebang, the
+// marker carrying the version and checksum of the body, then the body.
+func renderHook(name string) ([]byte, error) {
+	template, err := hookTemplates.ReadFile("hooks/" + name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown hook '%s': %w", name, err)
+	}
+	shebang, body, _ := bytes.Cut(template, []byte("\n"))
+	var out bytes.Buffer
+	out.Write(shebang)
+	fmt.Fprintf(&out, "\n%s hook=%s version=%d sha256=%s\n", hookMarker, name, HookVersion, checksum(body))
+	out.Write(body)
+	return out.Bytes(), nil
+}
+
+// writeHook installs the template for name into dir.
+func writeHook(dir, name string) error {
+	content, err := renderHook(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, content, 0755); err != nil {
+		return err
+	}
+	// WriteFile keeps the mode of a hook it overwrites.
+	return os.Chmod(path, 0755)
+}
+
+// readHook inspects the hook called name in dir.
+func readHook(dir, name string) (HookStatus, error) {
+	status := HookStatus{Name: name}
+	content, err := os.ReadFile(filepath.Join(dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		status.State = HookMissing
+		return status, nil
+	}
+	if err != nil {
+		return status, fmt.Errorf("failed to read hook '%s': %w", name, err)
+	}
+
+	version, sum, body, ok := parseHook(content)
+	switch {
+	case !ok:
+		status.State = HookUnmanaged
+	case sum != checksum(body):
+		status.State = HookModified
+		status.Version = version
+	case version < HookVersion:
+		status.State = HookOutdated
+		status.Version = version
+	default:
+		status.State = HookCurrent
+		status.Version = version
+	}
+	return status, nil
+}
+
+// parseHook splits an installed hook into the version and checksum from its
+// marker line and the body that follows the marker.
+func parseHook(content []byte) (version int, sum string, body []byte, ok bool) {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	offset := 0
+	for line := 0; scanner.Scan() && line < 2; line++ {
+		text := scanner.Text()
+		offset += len(text) + 1
+		if !strings.HasPrefix(text, hookMarker) {
+			continue
+		}
+		for _, field := range strings.Fields(strings.TrimPrefix(text, hookMarker)) {
+			key, value, _ := strings.Cut(field, "=")
+			switch key {
+			case "version":
+				version, _ = strconv.Atoi(value)
+			case "sha256":
+				sum = value
+			}
+		}
+		if offset > len(content) {
+			offset = len(content)
+		}
+		return version, sum, content[offset:], true
+	}
+	return 0, "", nil, false
+}
+
+// checksum returns the hex SHA-256 of data.
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
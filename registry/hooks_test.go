@@ -0,0 +1,110 @@
+// hooks_test.go
+package registry
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func hookStates(t *testing.T, results []HookResult) map[string]HookStatus {
+	t.Helper()
+	if len(results) != 1 || results[0].Err != nil {
+		t.Fatalf("Expected one successful result, got %+v", results)
+	}
+	states := make(map[string]HookStatus)
+	for _, hook := range results[0].Hooks {
+		states[hook.Name] = hook
+	}
+	return states
+}
+
+func TestRegistryHooks(t *testing.T) {
+	projects := t.TempDir()
+	path := writeRepo(t, projects, "demo", nil)
+	hooks := filepath.Join(path, ".git", "hooks")
+	os.MkdirAll(hooks, 0755)
+	os.WriteFile(filepath.Join(hooks, "commit-msg"), []byte("#!/bin/sh\nexit 0\n"), 0755)
+
+	reg, err := NewRegistry(WithProjectsPath(projects), WithStatePath(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	defer reg.Shutdown(ctx)
+
+	results, err := reg.Hooks(ctx, HookInstall, HookOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	states := hookStates(t, results)
+	if s := states["pre-commit"]; s.State != HookCurrent || !s.Changed || s.Version != HookVersion {
+		t.Errorf("Expected pre-commit to be installed, got %+v", s)
+	}
+	if s := states["commit-msg"]; s.State != HookUnmanaged || !s.Skipped {
+		t.Errorf("Expected the user's commit-msg to be left alone, got %+v", s)
+	}
+	content, _ := os.ReadFile(filepath.Join(hooks, "pre-commit"))
+	if !strings.HasPrefix(string(content), "#!/bin/sh\n"+hookMarker) {
+		t.Errorf("Expected the marker after the shebang, got:\n%s", content)
+	}
+
+	// An older template and a hand-edited hook.
+	rendered, _ := renderHook("pre-push")
+	old := strings.Replace(string(rendered), "version=1", "version=0", 1)
+	os.WriteFile(filepath.Join(hooks, "pre-push"), []byte(old), 0755)
+	os.WriteFile(filepath.Join(hooks, "pre-commit"), append(content, "echo edited\n"...), 0755)
+
+	item, err := reg.Item("demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]HookState{"pre-commit": HookModified, "commit-msg": HookUnmanaged, "pre-push": HookOutdated}
+	for _, hook := range item.Hooks {
+		if hook.State != want[hook.Name] {
+			t.Errorf("Expected %s to be %s, got %s", hook.Name, want[hook.Name], hook.State)
+		}
+	}
+
+	states = hookStates(t, mustHooks(t, reg, HookUpdate, HookOptions{}))
+	if s := states["pre-push"]; s.State != HookCurrent || !s.Changed {
+		t.Errorf("Expected pre-push to be updated, got %+v", s)
+	}
+	if s := states["pre-commit"]; !s.Skipped {
+		t.Errorf("Expected the edited pre-commit to be skipped, got %+v", s)
+	}
+
+	hookStates(t, mustHooks(t, reg, HookRemove, HookOptions{}))
+	if _, err := os.Stat(filepath.Join(hooks, "pre-push")); !os.IsNotExist(err) {
+		t.Error("Expected the managed pre-push to be removed")
+	}
+	if _, err := os.Stat(filepath.Join(hooks, "commit-msg")); err != nil {
+		t.Error("Expected the user's commit-msg to be kept")
+	}
+
+	states = hookStates(t, mustHooks(t, reg, HookInstall, HookOptions{Repos: []string{"demo"}, Force: true}))
+	for name, status := range states {
+		if status.State != HookCurrent {
+			t.Errorf("Expected a forced install to replace %s, got %+v", name, status)
+		}
+	}
+
+	if results, _ := reg.Hooks(ctx, HookInstall, HookOptions{Group: "none"}); len(results) != 0 {
+		t.Errorf("Expected no repositories in an unknown group, got %+v", results)
+	}
+}
+
+func mustHooks(t *testing.T, reg *Registry, action HookAction, opts HookOptions) []HookResult {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	results, err := reg.Hooks(ctx, action, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return results
+}
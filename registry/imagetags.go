@@ -0,0 +1,216 @@
+// File: registry/imagetags.go
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"golang.org/x/mod/semver"
+)
+
+// Labels set on every image the registry builds. LabelTags keeps the tags
+// given at build time, so an image moved off a tag still records it.
+const (
+	LabelRepo   = "io.cdaprod.registry.repo"
+	LabelCommit = "io.cdaprod.registry.commit"
+	LabelTags   = "io.cdaprod.registry.tags"
+)
+
+// DefaultTagTemplates are used for repositories whose manifest sets no
+// docker.tags.
+var DefaultTagTemplates = []string{
+	"{{.SHA}}{{.Dirty}}",
+	"{{.Branch}}{{.Dirty}}",
+	"{{.Version}}{{.Dirty}}",
+	"latest",
+}
+
+// maxTagLength is the longest tag Docker accepts.
+const maxTagLength = 128
+
+// invalidTagChars matches what Docker does not allow in a tag.
+var invalidTagChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// ImageMetadata describes the Git state an image is built from.
+type ImageMetadata struct {
+	Repo    string
+	Commit  string // Full hash of HEAD, empty before the first commit
+	SHA     string // Abbreviated hash of HEAD
+	Branch  string // Sanitised for use as a tag, empty when HEAD is detached
+	Version string // Semver of the nearest tag without its "v", if any
+	Dirty   bool
+}
+
+// ReadImageMetadata asks a RepoActor for the Git state to tag an image
+// with. The reply is an ImageMetadata.
+type ReadImageMetadata struct{}
+
+// Tags renders templates into image tags. Templates are text/template
+// strings over Repo, Commit, SHA, Branch, Version and Dirty ("-dirty" for
+// a dirty tree, otherwise empty). A template using a value that is not
+// known, such as Version without a semver tag, is skipped. Results are
+// sanitised and duplicates dropped.
+func (m ImageMetadata) Tags(templates []string) ([]string, error) {
+	data := map[string]string{"Repo": m.Repo, "Dirty": ""}
+	if m.Dirty {
+		data["Dirty"] = "-dirty"
+	}
+	if m.Commit != "" {
+		data["Commit"] = m.Commit
+		data["SHA"] = m.SHA
+	}
+	if m.Branch != "" {
+		data["Branch"] = m.Branch
+	}
+	if m.Version != "" {
+		data["Version"] = m.Version
+	}
+
+	var tags []string
+	seen := make(map[string]bool)
+	for _, text := range templates {
+		tmpl, err := parseTagTemplate(text)
+		if err != nil {
+			return nil, err
+		}
+		var b strings.Builder
+		if err := tmpl.Execute(&b, data); err != nil {
+			continue
+		}
+		tag := sanitizeTag(b.String())
+		if tag == "" && b.Len() > 0 {
+			fmt.Printf("Skipping tag %q of template %q: it has no characters valid in a tag.\n", b.String(), text)
+		}
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// parseTagTemplate compiles one tag template.
+func parseTagTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("tag").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tag template %q: %w", text, err)
+	}
+	return tmpl, nil
+}
+
+// sanitizeTag turns s into a valid Docker tag: runs of other characters
+// become "-", it is cut to maxTagLength, and it cannot start or end with
+// "." or "-". Nothing may be left of s.
+func sanitizeTag(s string) string {
+	tag := invalidTagChars.ReplaceAllString(s, "-")
+	tag = strings.TrimLeft(tag, ".-")
+	if len(tag) > maxTagLength {
+		tag = tag[:maxTagLength]
+	}
+	return strings.TrimRight(tag, ".-")
+}
+
+// readImageMetadata reads the Git state of the repository for tagging. A
+// directory that is not a Git repository only has its name.
+func (r *RepoActor) readImageMetadata() (ImageMetadata, error) {
+	metadata := ImageMetadata{Repo: r.Name}
+	status, err := r.refreshStatus()
+	if errors.Is(err, ErrNotGitRepo) {
+		return metadata, nil
+	}
+	if err != nil {
+		return metadata, err
+	}
+	metadata.Commit = status.Head
+	metadata.SHA = status.ShortHead()
+	metadata.Branch = sanitizeTag(status.Branch)
+	if metadata.Branch == "" && status.Branch != "" {
+		fmt.Printf("Branch '%s' of '%s' has no characters valid in a tag; its branch tags are skipped.\n", status.Branch, r.Name)
+	}
+	metadata.Dirty = status.Dirty
+	if status.Head == "" {
+		return metadata, nil
+	}
+
+	r.mu.RLock()
+	repo := r.gitRepo
+	r.mu.RUnlock()
+	metadata.Version, err = nearestVersion(repo, plumbing.NewHash(status.Head))
+	if err != nil {
+		return metadata, fmt.Errorf("failed to read tags of '%s': %w", r.Name, err)
+	}
+	return metadata, nil
+}
+
+// nearestVersion returns the highest semver tag among the commits closest to
+// head that have one, counting commits along any parent, without its "v",
+// or "" when there is none within maxAheadBehindWalk commits.
+func nearestVersion(repo *git.Repository, head plumbing.Hash) (string, error) {
+	versions := make(map[plumbing.Hash][]string)
+	refs, err := repo.Tags()
+	if err != nil {
+		return "", err
+	}
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		version := ref.Name().Short()
+		if !strings.HasPrefix(version, "v") {
+			version = "v" + version
+		}
+		if !semver.IsValid(version) {
+			return nil
+		}
+		target := ref.Hash()
+		// Annotated tags point at a tag object rather than the commit.
+		if tag, err := repo.TagObject(target); err == nil {
+			commit, err := tag.Commit()
+			if err != nil {
+				return nil
+			}
+			target = commit.Hash
+		}
+		versions[target] = append(versions[target], version)
+		return nil
+	})
+	if err != nil || len(versions) == 0 {
+		return "", err
+	}
+
+	// Walk breadth-first so every commit as close to head is looked at
+	// before any further away, along all parents of a merge.
+	var nearest string
+	visited := map[plumbing.Hash]bool{head: true}
+	level := []plumbing.Hash{head}
+	for walked := 0; len(level) > 0 && nearest == "" && walked < maxAheadBehindWalk; {
+		var next []plumbing.Hash
+		for _, hash := range level {
+			for _, version := range versions[hash] {
+				if nearest == "" || semver.Compare(version, nearest) > 0 {
+					nearest = version
+				}
+			}
+			walked++
+			commit, err := repo.CommitObject(hash)
+			if errors.Is(err, plumbing.ErrObjectNotFound) {
+				// History ends early in shallow clones.
+				continue
+			}
+			if err != nil {
+				return "", err
+			}
+			for _, parent := range commit.ParentHashes {
+				if !visited[parent] {
+					visited[parent] = true
+					next = append(next, parent)
+				}
+			}
+		}
+		level = next
+	}
+	return strings.TrimPrefix(nearest, "v"), nil
+}
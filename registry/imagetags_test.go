@@ -0,0 +1,157 @@
+// imagetags_test.go
+package registry
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestImageMetadataTags(t *testing.T) {
+	metadata := ImageMetadata{Repo: "api", Commit: "1a2b3c4d5e", SHA: "1a2b3c4", Branch: "feature-login", Version: "1.2.0"}
+	tags, err := metadata.Tags(DefaultTagTemplates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"1a2b3c4", "feature-login", "1.2.0", "latest"}; !reflect.DeepEqual(tags, want) {
+		t.Errorf("Expected %v, got %v", want, tags)
+	}
+
+	// Unknown values skip their template; dirty trees are marked.
+	detached := ImageMetadata{Repo: "api", Commit: "1a2b3c4d5e", SHA: "1a2b3c4", Dirty: true}
+	tags, _ = detached.Tags(append(DefaultTagTemplates, "{{.Repo}}/{{.SHA}}", "latest"))
+	if want := []string{"1a2b3c4-dirty", "latest", "api-1a2b3c4"}; !reflect.DeepEqual(tags, want) {
+		t.Errorf("Expected %v, got %v", want, tags)
+	}
+
+	if _, err := metadata.Tags([]string{"{{.SHA"}); err == nil {
+		t.Error("Expected an error for an invalid template")
+	}
+	long := "feature/" + strings.Repeat("a", 119) + ".-more"
+	tests := []struct{ branch, want string }{
+		{"-feature/Login#2", "feature-Login-2"},
+		{"release/1.0.", "release-1.0"},
+		{long, "feature-" + strings.Repeat("a", 119)},
+		{"###", ""},
+	}
+	for _, tt := range tests {
+		if tag := sanitizeTag(tt.branch); tag != tt.want {
+			t.Errorf("sanitizeTag(%q) = %q, want %q", tt.branch, tag, tt.want)
+		}
+	}
+}
+
+func TestReadImageMetadata(t *testing.T) {
+	projects := t.TempDir()
+	path := filepath.Join(projects, "api")
+	repo, err := git.PlainInit(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := commitFile(t, repo, "a.txt", "one")
+	if _, err := repo.CreateTag("v1.0.0", first, nil); err != nil {
+		t.Fatal(err)
+	}
+	second := commitFile(t, repo, "a.txt", "two")
+	tagger := &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()}
+	if _, err := repo.CreateTag("v1.1.0", second, &git.CreateTagOptions{Tagger: tagger, Message: "v1.1.0"}); err != nil {
+		t.Fatal(err)
+	}
+	repo.CreateTag("nightly", second, nil)
+	head := commitFile(t, repo, "a.txt", "three")
+
+	worktree, _ := repo.Worktree()
+	branch := plumbing.NewBranchReferenceName("feature/login")
+	if err := worktree.Checkout(&git.CheckoutOptions{Branch: branch, Create: true}); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(path, "a.txt"), []byte("dirty"), 0644)
+
+	reg, err := NewRegistry(WithProjectsPath(projects), WithStatePath(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reg.RegistryActor.Mailbox.Close()
+	actor, err := reg.RegistryActor.lookup("api")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	reply, err := Ask(ctx, actor.Mailbox, ReadImageMetadata{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ImageMetadata{
+		Repo:    "api",
+		Commit:  head.String(),
+		SHA:     head.String()[:7],
+		Branch:  "feature-login",
+		Version: "1.1.0",
+		Dirty:   true,
+	}
+	if metadata := reply.(ImageMetadata); metadata != want {
+		t.Errorf("Expected %+v, got %+v", want, metadata)
+	}
+
+	tags, err := imageTags(want, &Manifest{Docker: &DockerManifest{Image: "example/api:1.0", Tags: []string{"{{.Version}}", "{{.Branch}}-{{.SHA}}"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"example/api:1.1.0", "example/api:feature-login-" + want.SHA}; !reflect.DeepEqual(tags, expected) {
+		t.Errorf("Expected %v, got %v", expected, tags)
+	}
+}
+
+func TestNearestVersionAcrossMerge(t *testing.T) {
+	repo, err := git.PlainInit(t.TempDir(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := commitFile(t, repo, "a.txt", "base")
+	repo.CreateTag("v2.0.0", base, nil)
+
+	worktree, _ := repo.Worktree()
+	if err := worktree.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("feature"), Create: true}); err != nil {
+		t.Fatal(err)
+	}
+	feature := commitFile(t, repo, "b.txt", "feature")
+	repo.CreateTag("v1.1.0", feature, nil)
+	if err := worktree.Checkout(&git.CheckoutOptions{Branch: plumbing.Master}); err != nil {
+		t.Fatal(err)
+	}
+	var main plumbing.Hash
+	for i := 0; i < 3; i++ {
+		main = commitFile(t, repo, "a.txt", fmt.Sprintf("main %d", i))
+	}
+	// The merge's first parent is three commits from v2.0.0, its second
+	// parent is tagged v1.1.0.
+	merge, err := worktree.Commit("merge feature", &git.CommitOptions{
+		Author:  &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()},
+		Parents: []plumbing.Hash{main, feature},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	version, err := nearestVersion(repo, merge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != "1.1.0" {
+		t.Errorf("Expected the nearer tag on the second parent, 1.1.0, got %q", version)
+	}
+	if version, _ := nearestVersion(repo, main); version != "2.0.0" {
+		t.Errorf("Expected 2.0.0 along the first parent, got %q", version)
+	}
+}
@@ -0,0 +1,274 @@
+// File: registry/infer.go
+package registry
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// EdgeSource says where a dependency edge came from.
+type EdgeSource string
+
+const (
+	SourceManual      EdgeSource = "manual"
+	SourceManifest    EdgeSource = "manifest"
+	SourceGoMod       EdgeSource = "go.mod"
+	SourcePackageJSON EdgeSource = "package.json"
+	SourceDockerfile  EdgeSource = "Dockerfile"
+)
+
+// Edge is a dependency of one repository on another.
+type Edge struct {
+	From   string     `json:"from"`
+	To     string     `json:"to"`
+	Source EdgeSource `json:"source"`
+	Detail string     `json:"detail,omitempty"`
+}
+
+func (e Edge) String() string {
+	if e.Detail == "" {
+		return fmt.Sprintf("%s -> %s (%s)", e.From, e.To, e.Source)
+	}
+	return fmt.Sprintf("%s -> %s (%s: %s)", e.From, e.To, e.Source, e.Detail)
+}
+
+// edgesFrom turns a plain dependency list into edges from repo.
+func edgesFrom(repo string, deps []string, source EdgeSource) []Edge {
+	edges := make([]Edge, 0, len(deps))
+	for _, dep := range deps {
+		edges = append(edges, Edge{From: repo, To: dep, Source: source})
+	}
+	return edges
+}
+
+// InferDependencies derives dependency edges between the given repositories
+// (name to path) from what they already declare:
+//
+//   - go.mod require and replace directives naming a sibling module,
+//   - package.json file:, link: and workspace: references,
+//   - Dockerfile FROM lines naming an image another repository builds.
+//
+// manifests may be nil. Only repositories that build an image, with a
+// Dockerfile or a manifest docker section, can be depended on through FROM;
+// their image is named after the repository or the manifest's docker.image.
+func InferDependencies(repos map[string]string, manifests map[string]*Manifest) map[string][]Edge {
+	modules := make(map[string]string)  // Go module path -> repo
+	packages := make(map[string]string) // npm package name -> repo
+	images := make(map[string]string)   // image name without tag -> repo
+	paths := make(map[string]string)    // cleaned absolute path -> repo
+
+	names := make([]string, 0, len(repos))
+	for name := range repos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		path := repos[name]
+		if abs, err := filepath.Abs(path); err == nil {
+			paths[abs] = name
+		}
+		if module := goModulePath(path); module != "" {
+			modules[module] = name
+		}
+		if pkg, err := readPackageJSON(path); err == nil && pkg.Name != "" {
+			packages[pkg.Name] = name
+		}
+		for _, image := range builtImages(name, path, manifests[name]) {
+			images[image] = name
+		}
+	}
+
+	// resolvePath maps a path relative to a repository onto another repository.
+	resolvePath := func(from, rel string) (string, bool) {
+		target := filepath.Clean(filepath.Join(repos[from], rel))
+		if abs, err := filepath.Abs(target); err == nil {
+			target = abs
+		}
+		repo, ok := paths[target]
+		return repo, ok
+	}
+
+	inferred := make(map[string][]Edge)
+	for _, name := range names {
+		var edges []Edge
+		edges = append(edges, inferGoMod(name, repos[name], modules, resolvePath)...)
+		edges = append(edges, inferPackageJSON(name, repos[name], packages, resolvePath)...)
+		edges = append(edges, inferDockerfile(name, repos[name], images)...)
+		if len(edges) > 0 {
+			inferred[name] = edges
+		}
+	}
+	return inferred
+}
+
+// builtImages returns the names of the images a repository builds: its
+// default image name and its manifest's docker.image. A repository without a
+// Dockerfile or a manifest docker section builds none.
+func builtImages(name, path string, manifest *Manifest) []string {
+	if manifest != nil && manifest.Docker != nil {
+		if manifest.Docker.Image != "" {
+			return []string{defaultImageRepository(name), imageName(manifest.Docker.Image)}
+		}
+		if manifest.Docker.Dockerfile != "" {
+			return []string{defaultImageRepository(name)}
+		}
+	}
+	if _, err := os.Stat(filepath.Join(path, "Dockerfile")); err == nil {
+		return []string{defaultImageRepository(name)}
+	}
+	return nil
+}
+
+// goModulePath returns the module path declared in the repository's go.mod.
+func goModulePath(repoPath string) string {
+	data, err := os.ReadFile(filepath.Join(repoPath, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	return modfile.ModulePath(data)
+}
+
+// inferGoMod finds require and replace directives naming sibling modules.
+func inferGoMod(name, repoPath string, modules map[string]string, resolvePath func(string, string) (string, bool)) []Edge {
+	path := filepath.Join(repoPath, "go.mod")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	file, err := modfile.Parse(path, data, nil)
+	if err != nil {
+		fmt.Printf("Skipping go.mod of '%s': %v\n", name, err)
+		return nil
+	}
+
+	var edges []Edge
+	for _, req := range file.Require {
+		if dep, ok := modules[req.Mod.Path]; ok && dep != name {
+			edges = append(edges, Edge{From: name, To: dep, Source: SourceGoMod, Detail: "require " + req.Mod.Path})
+		}
+	}
+	for _, rep := range file.Replace {
+		detail := fmt.Sprintf("replace %s => %s", rep.Old.Path, rep.New.Path)
+		if modfile.IsDirectoryPath(rep.New.Path) {
+			if dep, ok := resolvePath(name, rep.New.Path); ok && dep != name {
+				edges = append(edges, Edge{From: name, To: dep, Source: SourceGoMod, Detail: detail})
+			}
+		} else if dep, ok := modules[rep.New.Path]; ok && dep != name {
+			edges = append(edges, Edge{From: name, To: dep, Source: SourceGoMod, Detail: detail})
+		}
+	}
+	return edges
+}
+
+// packageJSON holds the package.json fields used for inference.
+type packageJSON struct {
+	Name                 string            `json:"name"`
+	Dependencies         map[string]string `json:"dependencies"`
+	DevDependencies      map[string]string `json:"devDependencies"`
+	PeerDependencies     map[string]string `json:"peerDependencies"`
+	OptionalDependencies map[string]string `json:"optionalDependencies"`
+}
+
+func readPackageJSON(repoPath string) (*packageJSON, error) {
+	data, err := os.ReadFile(filepath.Join(repoPath, "package.json"))
+	if err != nil {
+		return nil, err
+	}
+	pkg := &packageJSON{}
+	if err := json.Unmarshal(data, pkg); err != nil {
+		return nil, err
+	}
+	return pkg, nil
+}
+
+// inferPackageJSON finds file:, link: and workspace: references to sibling
+// packages.
+func inferPackageJSON(name, repoPath string, packages map[string]string, resolvePath func(string, string) (string, bool)) []Edge {
+	pkg, err := readPackageJSON(repoPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Printf("Skipping package.json of '%s': %v\n", name, err)
+		}
+		return nil
+	}
+
+	var edges []Edge
+	for _, deps := range []map[string]string{pkg.Dependencies, pkg.DevDependencies, pkg.PeerDependencies, pkg.OptionalDependencies} {
+		specs := make([]string, 0, len(deps))
+		for spec := range deps {
+			specs = append(specs, spec)
+		}
+		sort.Strings(specs)
+		for _, spec := range specs {
+			version := deps[spec]
+			detail := fmt.Sprintf("%s: %s", spec, version)
+			switch {
+			case strings.HasPrefix(version, "file:"), strings.HasPrefix(version, "link:"):
+				rel := version[strings.Index(version, ":")+1:]
+				if dep, ok := resolvePath(name, rel); ok && dep != name {
+					edges = append(edges, Edge{From: name, To: dep, Source: SourcePackageJSON, Detail: detail})
+				}
+			case strings.HasPrefix(version, "workspace:"):
+				if dep, ok := packages[spec]; ok && dep != name {
+					edges = append(edges, Edge{From: name, To: dep, Source: SourcePackageJSON, Detail: detail})
+				}
+			}
+		}
+	}
+	return edges
+}
+
+// inferDockerfile finds FROM lines naming an image another repository builds.
+func inferDockerfile(name, repoPath string, images map[string]string) []Edge {
+	data, err := os.ReadFile(filepath.Join(repoPath, "Dockerfile"))
+	if err != nil {
+		return nil
+	}
+
+	var edges []Edge
+	stages := make(map[string]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || !strings.EqualFold(fields[0], "FROM") {
+			continue
+		}
+		args := fields[1:]
+		for len(args) > 0 && strings.HasPrefix(args[0], "--") {
+			args = args[1:]
+		}
+		if len(args) == 0 {
+			continue
+		}
+		image := args[0]
+		if !stages[strings.ToLower(image)] && !strings.Contains(image, "$") {
+			if dep, ok := images[imageName(image)]; ok && dep != name {
+				edges = append(edges, Edge{From: name, To: dep, Source: SourceDockerfile, Detail: "FROM " + image})
+			}
+		}
+		if len(args) >= 3 && strings.EqualFold(args[1], "AS") {
+			stages[strings.ToLower(args[2])] = true
+		}
+	}
+	return edges
+}
+
+// imageName strips the tag and digest from an image reference.
+func imageName(ref string) string {
+	if i := strings.Index(ref, "@"); i >= 0 {
+		ref = ref[:i]
+	}
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		ref = ref[:i]
+	}
+	return ref
+}
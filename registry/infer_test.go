@@ -0,0 +1,113 @@
+// infer_test.go
+package registry
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	git "github.com/go-git/go-git/v5"
+)
+
+// writeRepo creates a git repository under dir with the given files.
+func writeRepo(t *testing.T, dir, name string, files map[string]string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if _, err := git.PlainInit(path, false); err != nil {
+		t.Fatal(err)
+	}
+	for file, content := range files {
+		if err := os.WriteFile(filepath.Join(path, file), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+func TestInferDependencies(t *testing.T) {
+	projects := t.TempDir()
+	repos := map[string]string{
+		"core": writeRepo(t, projects, "core", map[string]string{
+			"go.mod":     "module example.com/core\n\ngo 1.20\n",
+			"Dockerfile": "FROM golang:1.20\n",
+		}),
+		"lib": writeRepo(t, projects, "lib", map[string]string{
+			"go.mod": "module example.com/lib\n\ngo 1.20\n\nrequire example.com/core v0.1.0\n",
+		}),
+		"tools": writeRepo(t, projects, "tools", map[string]string{
+			"go.mod": "module example.com/tools\n\ngo 1.20\n\nreplace example.com/anything => ../lib\n",
+		}),
+		"ui-kit": writeRepo(t, projects, "ui-kit", map[string]string{
+			"package.json": `{"name": "@acme/ui-kit"}`,
+		}),
+		"web": writeRepo(t, projects, "web", map[string]string{
+			"package.json": `{"name": "web", "dependencies": {"@acme/ui-kit": "workspace:*", "left-pad": "^1.0.0"}, "devDependencies": {"tools": "file:../tools"}}`,
+			"Dockerfile":   "FROM node:20\n",
+		}),
+		// Builds no image, so FROM node must not point at it.
+		"node": writeRepo(t, projects, "node", map[string]string{
+			"package.json": `{"name": "node-scripts"}`,
+		}),
+		"api": writeRepo(t, projects, "api", map[string]string{
+			"Dockerfile": "FROM --platform=$BUILDPLATFORM acme/core-base:1.2 AS build\nFROM build\nFROM core:latest\n",
+		}),
+	}
+	manifests := map[string]*Manifest{
+		"core": {Docker: &DockerManifest{Image: "acme/core-base:dev"}},
+	}
+
+	inferred := InferDependencies(repos, manifests)
+
+	targets := func(edges []Edge) []string {
+		var deps []string
+		for _, edge := range edges {
+			deps = append(deps, edge.To+" "+string(edge.Source))
+		}
+		return deps
+	}
+	want := map[string][]string{
+		"lib":   {"core go.mod"},
+		"tools": {"lib go.mod"},
+		"web":   {"ui-kit package.json", "tools package.json"},
+		"api":   {"core Dockerfile", "core Dockerfile"},
+	}
+	if len(inferred) != len(want) {
+		t.Errorf("Expected edges for %d repositories, got %v", len(want), inferred)
+	}
+	for repo, deps := range want {
+		if got := targets(inferred[repo]); !reflect.DeepEqual(got, deps) {
+			t.Errorf("%s: expected %v, got %v", repo, deps, got)
+		}
+	}
+	if detail := inferred["api"][0].Detail; detail != "FROM acme/core-base:1.2" {
+		t.Errorf("Unexpected edge detail %q", detail)
+	}
+}
+
+func TestManifestOverridesInferredDependencies(t *testing.T) {
+	projects := t.TempDir()
+	writeRepo(t, projects, "core", map[string]string{"go.mod": "module example.com/core\n"})
+	writeRepo(t, projects, "extra", nil)
+	writeRepo(t, projects, "lib", map[string]string{
+		"go.mod": "module example.com/lib\n\nrequire example.com/core v0.1.0\n",
+	})
+	writeRepo(t, projects, "app", map[string]string{
+		"go.mod":     "module example.com/app\n\nrequire example.com/core v0.1.0\n",
+		ManifestFile: "dependsOn: [extra]\n",
+	})
+
+	reg, err := NewRegistry(WithProjectsPath(projects), WithStatePath(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reg.RegistryActor.Mailbox.Close()
+
+	edges := reg.Coordinator.Edges()
+	if len(edges["lib"]) != 1 || edges["lib"][0].Source != SourceGoMod || edges["lib"][0].To != "core" {
+		t.Errorf("Expected lib to depend on core through go.mod, got %v", edges["lib"])
+	}
+	if len(edges["app"]) != 1 || edges["app"][0].Source != SourceManifest || edges["app"][0].To != "extra" {
+		t.Errorf("Expected the manifest to override app's inferred dependencies, got %v", edges["app"])
+	}
+}
@@ -0,0 +1,345 @@
+// File: registry/journal.go
+package registry
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Journal entry types, named after the message that produced them.
+const (
+	JournalAddRepo           = "AddRepo"
+	JournalRemoveRepo        = "RemoveRepo"
+	JournalToggleRepo        = "ToggleRepo"
+	JournalConfigureDocker   = "ConfigureDocker"
+	JournalConfigurePipeline = "ConfigurePipeline"
+)
+
+// journalCompactThreshold is the number of live entries after which the
+// journal is compacted into the state snapshot.
+const journalCompactThreshold = 500
+
+// JournalEntry records one state-changing message.
+type JournalEntry struct {
+	Seq    uint64     `json:"seq"`
+	Time   time.Time  `json:"time"`
+	Type   string     `json:"type"`
+	Repo   string     `json:"repo"`
+	Active *bool      `json:"active,omitempty"`
+	State  *RepoState `json:"state,omitempty"`
+}
+
+// Describe returns a short human readable summary of the entry.
+func (e JournalEntry) Describe() string {
+	switch e.Type {
+	case JournalAddRepo:
+		if e.State != nil {
+			return fmt.Sprintf("added from %s", e.State.Path)
+		}
+		return "added"
+	case JournalRemoveRepo:
+		return "removed"
+	case JournalToggleRepo:
+		if e.Active != nil && *e.Active {
+			return "enabled"
+		}
+		return "disabled"
+	case JournalConfigureDocker:
+		return "Dockerfile added"
+	case JournalConfigurePipeline:
+		return "pipeline added"
+	}
+	return e.Type
+}
+
+// Journal is an append-only log of state-changing messages. Entries folded
+// into a snapshot are moved to an archive file by Compact, so the live log
+// stays short while the full history remains available.
+type Journal struct {
+	path        string
+	archivePath string
+	seq         uint64
+	archived    uint64 // Sequence number of the last archived entry
+	live        int
+	mu          sync.Mutex
+}
+
+// OpenJournal opens the journal at path, creating it on first append.
+func OpenJournal(path string) (*Journal, error) {
+	j := &Journal{path: path, archivePath: path + ".archive"}
+	if err := truncateTornWrite(j.path); err != nil {
+		return nil, err
+	}
+
+	if err := truncateTornWrite(j.archivePath); err != nil {
+		return nil, err
+	}
+
+	entries, err := readJournal(j.path)
+	if err != nil {
+		return nil, err
+	}
+	archived, err := readJournal(j.archivePath)
+	if err != nil {
+		return nil, err
+	}
+	if len(archived) > 0 {
+		j.archived = archived[len(archived)-1].Seq
+		j.seq = j.archived
+	}
+	j.live = len(entries)
+	if len(entries) > 0 && entries[len(entries)-1].Seq > j.seq {
+		j.seq = entries[len(entries)-1].Seq
+	}
+	return j, nil
+}
+
+// Seq returns the sequence number of the last appended entry.
+func (j *Journal) Seq() uint64 {
+	if j == nil {
+		return 0
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.seq
+}
+
+// Append assigns the next sequence number to entry and writes it durably.
+// Appending to a nil Journal is a no-op.
+func (j *Journal) Append(entry JournalEntry) error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entry.Seq = j.seq + 1
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	if err := appendJournal(j.path, []JournalEntry{entry}); err != nil {
+		return err
+	}
+	j.seq = entry.Seq
+	j.live++
+	return nil
+}
+
+// Entries returns the live entries with a sequence number above since.
+func (j *Journal) Entries(since uint64) ([]JournalEntry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entries, err := readJournal(j.path)
+	if err != nil {
+		return nil, err
+	}
+	return entriesAfter(entries, since), nil
+}
+
+// History returns every recorded entry, archived and live, for repo. An
+// empty repo returns the history of the whole registry.
+func (j *Journal) History(repo string) ([]JournalEntry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	archived, err := readJournal(j.archivePath)
+	if err != nil {
+		return nil, err
+	}
+	live, err := readJournal(j.path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Entries archived by a compaction that crashed before rewriting the
+	// live log appear in both files.
+	if len(archived) > 0 {
+		live = entriesAfter(live, archived[len(archived)-1].Seq)
+	}
+
+	var history []JournalEntry
+	for _, entry := range append(archived, live...) {
+		if repo == "" || entry.Repo == repo {
+			history = append(history, entry)
+		}
+	}
+	return history, nil
+}
+
+// NeedsCompaction reports whether the live log has grown past the threshold.
+func (j *Journal) NeedsCompaction() bool {
+	if j == nil {
+		return false
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.live > journalCompactThreshold
+}
+
+// Compact moves every entry up to and including upTo, which must already be
+// reflected in a saved snapshot, from the live log to the archive.
+func (j *Journal) Compact(upTo uint64) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entries, err := readJournal(j.path)
+	if err != nil {
+		return err
+	}
+	keep := entriesAfter(entries, upTo)
+	folded := entries[:len(entries)-len(keep)]
+	if len(folded) == 0 {
+		return nil
+	}
+
+	// Entries archived by a compaction that crashed before rewriting the
+	// live log are still live; they must not be archived twice.
+	if unarchived := entriesAfter(folded, j.archived); len(unarchived) > 0 {
+		if err := appendJournal(j.archivePath, unarchived); err != nil {
+			return fmt.Errorf("failed to archive journal entries: %w", err)
+		}
+		j.archived = unarchived[len(unarchived)-1].Seq
+	}
+
+	tmp := j.path + ".tmp"
+	os.Remove(tmp)
+	if err := appendJournal(tmp, keep); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, j.path); err != nil {
+		return fmt.Errorf("failed to replace journal '%s': %w", j.path, err)
+	}
+	j.live = len(keep)
+	return nil
+}
+
+// Apply replays a journal entry onto the state.
+func (s *State) Apply(entry JournalEntry) {
+	repo, exists := s.Repos[entry.Repo]
+	switch entry.Type {
+	case JournalAddRepo:
+		if !exists && entry.State != nil {
+			s.Repos[entry.Repo] = *entry.State
+		}
+	case JournalRemoveRepo:
+		delete(s.Repos, entry.Repo)
+		delete(s.Dependencies, entry.Repo)
+		delete(s.Edges, entry.Repo)
+	case JournalToggleRepo:
+		if exists && entry.Active != nil {
+			repo.Active = *entry.Active
+			repo.LastUpdated = entry.Time
+			s.Repos[entry.Repo] = repo
+		}
+	case JournalConfigureDocker:
+		if exists {
+			repo.IsDocker = true
+			repo.LastUpdated = entry.Time
+			s.Repos[entry.Repo] = repo
+		}
+	case JournalConfigurePipeline:
+		if exists {
+			repo.HasPipeline = true
+			repo.LastUpdated = entry.Time
+			s.Repos[entry.Repo] = repo
+		}
+	}
+	if entry.Seq > s.JournalSeq {
+		s.JournalSeq = entry.Seq
+	}
+}
+
+// readJournal reads every entry from a journal file. A missing file is empty.
+func readJournal(path string) ([]JournalEntry, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal '%s': %w", path, err)
+	}
+	defer f.Close()
+
+	var entries []JournalEntry
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// A line without a trailing newline is a torn write from a
+			// crash and is ignored.
+			return entries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read journal '%s': %w", path, err)
+		}
+		var entry JournalEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, fmt.Errorf("corrupt journal entry in '%s': %w", path, err)
+		}
+		entries = append(entries, entry)
+	}
+}
+
+// truncateTornWrite drops a trailing partial line left by a crash so later
+// appends start on a fresh line.
+func truncateTornWrite(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read journal '%s': %w", path, err)
+	}
+	if len(data) == 0 || data[len(data)-1] == '\n' {
+		return nil
+	}
+	end := bytes.LastIndexByte(data, '\n') + 1
+	if err := os.Truncate(path, int64(end)); err != nil {
+		return fmt.Errorf("failed to repair journal '%s': %w", path, err)
+	}
+	return nil
+}
+
+// appendJournal appends entries to a journal file and syncs it.
+func appendJournal(path string, entries []JournalEntry) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create journal directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open journal '%s': %w", path, err)
+	}
+	defer f.Close()
+
+	writer := bufio.NewWriter(f)
+	for _, entry := range entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("failed to encode journal entry: %w", err)
+		}
+		writer.Write(append(data, '\n'))
+	}
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write journal '%s': %w", path, err)
+	}
+	return f.Sync()
+}
+
+// entriesAfter returns the entries with a sequence number above seq.
+func entriesAfter(entries []JournalEntry, seq uint64) []JournalEntry {
+	for i, entry := range entries {
+		if entry.Seq > seq {
+			return entries[i:]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+// journal_test.go
+package registry
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJournalReplayAndCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.log")
+	journal, err := OpenJournal(path)
+	if err != nil {
+		t.Fatalf("OpenJournal failed: %v", err)
+	}
+
+	inactive := false
+	for _, entry := range []JournalEntry{
+		{Type: JournalAddRepo, Repo: "alpha", State: &RepoState{Name: "alpha", Path: "/tmp/alpha", Active: true}},
+		{Type: JournalConfigureDocker, Repo: "alpha"},
+		{Type: JournalToggleRepo, Repo: "alpha", Active: &inactive},
+	} {
+		if err := journal.Append(entry); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+
+	state := NewState()
+	entries, err := journal.Entries(state.JournalSeq)
+	if err != nil {
+		t.Fatalf("Entries failed: %v", err)
+	}
+	for _, entry := range entries {
+		state.Apply(entry)
+	}
+	if repo := state.Repos["alpha"]; repo.Active || !repo.IsDocker {
+		t.Errorf("Unexpected replayed state %+v", repo)
+	}
+	if state.JournalSeq != 3 {
+		t.Errorf("Expected JournalSeq 3, got %d", state.JournalSeq)
+	}
+
+	if err := journal.Compact(2); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	live, _ := journal.Entries(0)
+	if len(live) != 1 || live[0].Seq != 3 {
+		t.Errorf("Expected only entry 3 to stay live, got %v", live)
+	}
+	history, _ := journal.History("alpha")
+	if len(history) != 3 {
+		t.Errorf("Expected full history of 3 entries after compaction, got %d", len(history))
+	}
+}
+
+func TestJournalIgnoresTornWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.log")
+	journal, _ := OpenJournal(path)
+	journal.Append(JournalEntry{Type: JournalRemoveRepo, Repo: "alpha"})
+
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(`{"seq":2,"type":"Rem`)
+	f.Close()
+
+	reopened, err := OpenJournal(path)
+	if err != nil {
+		t.Fatalf("OpenJournal failed: %v", err)
+	}
+	if err := reopened.Append(JournalEntry{Type: JournalRemoveRepo, Repo: "beta"}); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	entries, err := reopened.Entries(0)
+	if err != nil {
+		t.Fatalf("Entries failed: %v", err)
+	}
+	if len(entries) != 2 || entries[1].Repo != "beta" || entries[1].Seq != 2 {
+		t.Errorf("Unexpected entries after repair: %+v", entries)
+	}
+}
+
+func TestJournalCompactionAfterCrash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.log")
+	journal, _ := OpenJournal(path)
+	for _, repo := range []string{"alpha", "beta", "gamma"} {
+		journal.Append(JournalEntry{Type: JournalRemoveRepo, Repo: repo})
+	}
+
+	// A compaction that crashed after archiving entries 1 and 2 but before
+	// rewriting the live log.
+	live, _ := journal.Entries(0)
+	if err := appendJournal(path+".archive", live[:2]); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := OpenJournal(path)
+	if err != nil {
+		t.Fatalf("OpenJournal failed: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := reopened.Compact(3); err != nil {
+			t.Fatalf("Compact failed: %v", err)
+		}
+	}
+	archived, _ := readJournal(path + ".archive")
+	if len(archived) != 3 || archived[2].Seq != 3 {
+		t.Errorf("Expected each entry archived once, got %+v", archived)
+	}
+	if live, _ := reopened.Entries(0); len(live) != 0 {
+		t.Errorf("Expected an empty live log, got %+v", live)
+	}
+	if err := reopened.Append(JournalEntry{Type: JournalRemoveRepo, Repo: "delta"}); err != nil || reopened.Seq() != 4 {
+		t.Errorf("Expected the next entry to be 4, got %d, %v", reopened.Seq(), err)
+	}
+}
@@ -0,0 +1,186 @@
+// File: registry/mailbox.go
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// DefaultMailboxCapacity is the number of messages an actor mailbox holds
+// before its OverflowPolicy applies.
+const DefaultMailboxCapacity = 64
+
+// Mailbox errors.
+var (
+	ErrMailboxFull    = errors.New("mailbox full")
+	ErrMailboxClosed  = errors.New("mailbox closed")
+	ErrMessageDropped = errors.New("message dropped from full mailbox")
+)
+
+// OverflowPolicy decides what Send does when a mailbox is full.
+type OverflowPolicy int
+
+const (
+	// Block waits for room until the sender's context is done.
+	Block OverflowPolicy = iota
+	// DropOldest discards the oldest queued message to make room.
+	DropOldest
+	// Reject fails the send with ErrMailboxFull.
+	Reject
+)
+
+func (p OverflowPolicy) String() string {
+	switch p {
+	case Block:
+		return "block"
+	case DropOldest:
+		return "drop-oldest"
+	case Reject:
+		return "reject"
+	}
+	return fmt.Sprintf("OverflowPolicy(%d)", int(p))
+}
+
+// Mailbox is a bounded FIFO queue of messages for a single actor.
+type Mailbox struct {
+	mu       sync.Mutex
+	queue    []Message
+	capacity int
+	policy   OverflowPolicy
+	closed   bool
+	dropped  uint64
+	// changed is closed and replaced whenever the queue changes, waking
+	// every goroutine blocked in Send or Receive.
+	changed chan struct{}
+}
+
+// NewMailbox initializes a new Mailbox. A capacity below one falls back to
+// DefaultMailboxCapacity.
+func NewMailbox(capacity int, policy OverflowPolicy) *Mailbox {
+	if capacity < 1 {
+		capacity = DefaultMailboxCapacity
+	}
+	return &Mailbox{
+		capacity: capacity,
+		policy:   policy,
+		changed:  make(chan struct{}),
+	}
+}
+
+// Send queues msg according to the mailbox's OverflowPolicy.
+func (m *Mailbox) Send(ctx context.Context, msg Message) error {
+	m.mu.Lock()
+	for {
+		if m.closed {
+			m.mu.Unlock()
+			return ErrMailboxClosed
+		}
+		if len(m.queue) < m.capacity {
+			m.push(msg)
+			m.mu.Unlock()
+			return nil
+		}
+
+		switch m.policy {
+		case DropOldest:
+			oldest := m.queue[0]
+			m.queue = m.queue[1:]
+			m.dropped++
+			m.push(msg)
+			m.mu.Unlock()
+			_, _, reply := unwrap(oldest)
+			reply(nil, ErrMessageDropped)
+			return nil
+		case Reject:
+			m.mu.Unlock()
+			return ErrMailboxFull
+		}
+
+		wait := m.changed
+		m.mu.Unlock()
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		m.mu.Lock()
+	}
+}
+
+// SendSelf queues a message an actor sends to itself from inside one of its
+// handlers. It ignores the capacity, because blocking there would wait on the
+// very goroutine that drains the mailbox.
+func (m *Mailbox) SendSelf(msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return ErrMailboxClosed
+	}
+	m.push(msg)
+	return nil
+}
+
+// Receive blocks until a message is available. It returns false once the
+// mailbox is closed and every queued message has been received.
+func (m *Mailbox) Receive() (Message, bool) {
+	m.mu.Lock()
+	for len(m.queue) == 0 {
+		if m.closed {
+			m.mu.Unlock()
+			return nil, false
+		}
+		wait := m.changed
+		m.mu.Unlock()
+		<-wait
+		m.mu.Lock()
+	}
+	msg := m.queue[0]
+	m.queue[0] = nil
+	m.queue = m.queue[1:]
+	m.notify()
+	m.mu.Unlock()
+	return msg, true
+}
+
+// Close stops the mailbox from accepting messages. Messages already queued
+// can still be received.
+func (m *Mailbox) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return
+	}
+	m.closed = true
+	m.notify()
+}
+
+// Len returns the number of queued messages.
+func (m *Mailbox) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.queue)
+}
+
+// Cap returns the mailbox capacity.
+func (m *Mailbox) Cap() int {
+	return m.capacity
+}
+
+// Dropped returns how many messages the DropOldest policy has discarded.
+func (m *Mailbox) Dropped() uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.dropped
+}
+
+func (m *Mailbox) push(msg Message) {
+	m.queue = append(m.queue, msg)
+	m.notify()
+}
+
+func (m *Mailbox) notify() {
+	close(m.changed)
+	m.changed = make(chan struct{})
+}
@@ -0,0 +1,141 @@
+// mailbox_test.go
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+)
+
+func TestMailboxOverflowPolicies(t *testing.T) {
+	ctx := context.Background()
+
+	reject := NewMailbox(1, Reject)
+	reject.Send(ctx, "first")
+	if err := reject.Send(ctx, "second"); !errors.Is(err, ErrMailboxFull) {
+		t.Errorf("Expected ErrMailboxFull, got %v", err)
+	}
+
+	drop := NewMailbox(1, DropOldest)
+	req := Request{Ctx: ctx, Msg: "first", ReplyTo: make(chan Reply, 1)}
+	drop.Send(ctx, req)
+	if err := drop.Send(ctx, "second"); err != nil {
+		t.Fatalf("DropOldest send failed: %v", err)
+	}
+	if reply := <-req.ReplyTo; !errors.Is(reply.Err, ErrMessageDropped) {
+		t.Errorf("Expected dropped request to be answered with ErrMessageDropped, got %v", reply.Err)
+	}
+	if msg, _ := drop.Receive(); msg != "second" {
+		t.Errorf("Expected newest message to survive, got %v", msg)
+	}
+
+	block := NewMailbox(1, Block)
+	block.Send(ctx, "first")
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := block.Send(timeout, "second"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected blocked send to time out, got %v", err)
+	}
+
+	block.Close()
+	if err := block.Send(ctx, "third"); !errors.Is(err, ErrMailboxClosed) {
+		t.Errorf("Expected ErrMailboxClosed, got %v", err)
+	}
+	if msg, ok := block.Receive(); !ok || msg != "first" {
+		t.Errorf("Expected queued message to be received after close, got %v", msg)
+	}
+	if _, ok := block.Receive(); ok {
+		t.Error("Expected Receive to report a closed, empty mailbox")
+	}
+}
+
+func TestMailboxStress(t *testing.T) {
+	const producers, perProducer = 16, 500
+	mailbox := NewMailbox(4, Block)
+
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < perProducer; i++ {
+				if err := mailbox.Send(context.Background(), fmt.Sprintf("%d-%d", p, i)); err != nil {
+					t.Errorf("Send failed: %v", err)
+					return
+				}
+			}
+		}(p)
+	}
+	go func() {
+		wg.Wait()
+		mailbox.Close()
+	}()
+
+	received := 0
+	for {
+		if _, ok := mailbox.Receive(); !ok {
+			break
+		}
+		received++
+	}
+	if received != producers*perProducer {
+		t.Errorf("Expected %d messages, received %d", producers*perProducer, received)
+	}
+}
+
+func TestRegistryActorStressUnderSmallMailboxes(t *testing.T) {
+	wg := &sync.WaitGroup{}
+	actor := NewRegistryActor(wg)
+	actor.SetMailboxOptions(1, Block)
+	actor.Start()
+	defer actor.Mailbox.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Scanning self-sends one AddRepo per repository into a mailbox that
+	// only holds one message.
+	projects := t.TempDir()
+	for i := 0; i < 5; i++ {
+		if _, err := git.PlainInit(filepath.Join(projects, fmt.Sprintf("repo-%d", i)), false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	found, err := Ask(ctx, actor.Mailbox, ScanDir{Directory: projects})
+	if err != nil {
+		t.Fatalf("ScanDir failed: %v", err)
+	}
+	if found.(int) != 5 {
+		t.Errorf("Expected 5 repositories, found %v", found)
+	}
+	if _, err := Ask(ctx, actor.Mailbox, Sync{}); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+
+	var callers sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		callers.Add(1)
+		go func(i int) {
+			defer callers.Done()
+			name := fmt.Sprintf("repo-%d", i%5)
+			if _, err := Ask(ctx, actor.Mailbox, ToggleRepo{Name: name}); err != nil {
+				t.Errorf("ToggleRepo %s failed: %v", name, err)
+			}
+			actor.ListItems()
+		}(i)
+	}
+	callers.Wait()
+
+	if _, err := Ask(ctx, actor.Mailbox, RemoveRepo{Name: "repo-0"}); err != nil {
+		t.Errorf("RemoveRepo failed: %v", err)
+	}
+	if got := len(actor.ListItems()); got != 4 {
+		t.Errorf("Expected 4 repositories after removal, got %d", got)
+	}
+}
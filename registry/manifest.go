@@ -0,0 +1,386 @@
+// File: registry/manifest.go
+package registry
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestFile is the name of the manifest a repository keeps at its root.
+const ManifestFile = ".registry.yaml"
+
+// ManifestVersion is the manifest schema version this package understands.
+const ManifestVersion = 1
+
+// validName matches group, profile and task names used in manifests.
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// validRepoName matches repository names, which are paths below the projects
+// directory such as "clients/acme/portal".
+var validRepoName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*(/[A-Za-z0-9][A-Za-z0-9._-]*)*$`)
+
+// Manifest describes a repository to the registry. It is read from
+// ManifestFile during discovery.
+type Manifest struct {
+	Version   int                     `yaml:"version"`
+	Enabled   *bool                   `yaml:"enabled,omitempty"`
+	DependsOn []string                `yaml:"dependsOn,omitempty"`
+	Groups    []string                `yaml:"groups,omitempty"`
+	Labels    map[string]string       `yaml:"labels,omitempty"`
+	Docker    *DockerManifest         `yaml:"docker,omitempty"`
+	Tasks     map[string]ManifestTask `yaml:"tasks,omitempty"`
+}
+
+// DockerManifest holds the Docker build settings of a repository. The
+// settings at its top level are the default build; each named profile is
+// applied on top of them. Tags are templates for the tags of built images,
+// see ImageMetadata.Tags.
+type DockerManifest struct {
+	Image        string   `yaml:"image,omitempty"`
+	Tags         []string `yaml:"tags,omitempty"`
+	BuildProfile `yaml:",inline"`
+	Profiles     map[string]BuildProfile `yaml:"profiles,omitempty"`
+}
+
+// BuildProfile is a set of Docker build settings. Paths are relative to the
+// repository root, and the Dockerfile must be inside the context.
+type BuildProfile struct {
+	Dockerfile string            `yaml:"dockerfile,omitempty"`
+	Context    string            `yaml:"context,omitempty"`
+	Target     string            `yaml:"target,omitempty"`
+	Args       map[string]string `yaml:"args,omitempty"`
+	Labels     map[string]string `yaml:"labels,omitempty"`
+	CacheFrom  []string          `yaml:"cacheFrom,omitempty"`
+	NoCache    bool              `yaml:"noCache,omitempty"`
+}
+
+// ErrUnknownProfile is returned for a build profile a manifest does not
+// declare.
+var ErrUnknownProfile = errors.New("unknown build profile")
+
+// merge returns p with the settings of override applied. Args and labels
+// are merged; other settings are replaced when override sets them.
+func (p BuildProfile) merge(override BuildProfile) BuildProfile {
+	merged := p
+	if override.Dockerfile != "" {
+		merged.Dockerfile = override.Dockerfile
+	}
+	if override.Context != "" {
+		merged.Context = override.Context
+	}
+	if override.Target != "" {
+		merged.Target = override.Target
+	}
+	merged.Args = mergeMaps(p.Args, override.Args)
+	merged.Labels = mergeMaps(p.Labels, override.Labels)
+	if override.CacheFrom != nil {
+		merged.CacheFrom = override.CacheFrom
+	}
+	merged.NoCache = p.NoCache || override.NoCache
+	return merged
+}
+
+// mergeMaps returns a new map holding base overridden by override.
+func mergeMaps(base, override map[string]string) map[string]string {
+	if base == nil && override == nil {
+		return nil
+	}
+	merged := make(map[string]string, len(base)+len(override))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range override {
+		merged[k] = v
+	}
+	return merged
+}
+
+// ManifestTask is a named command a repository declares. In YAML it is
+// either a command string or a mapping.
+type ManifestTask struct {
+	Description string            `yaml:"description,omitempty"`
+	Run         string            `yaml:"run"`
+	Dir         string            `yaml:"dir,omitempty"`
+	Env         map[string]string `yaml:"env,omitempty"`
+}
+
+// UnmarshalYAML accepts the `build: go build ./...` shorthand.
+func (t *ManifestTask) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		t.Run = node.Value
+		return nil
+	}
+	type plain ManifestTask
+	var task plain
+	if err := node.Decode(&task); err != nil {
+		return err
+	}
+	*t = ManifestTask(task)
+	return nil
+}
+
+// ManifestError lists every problem found in a repository's manifest.
+type ManifestError struct {
+	Path     string
+	Problems []string
+}
+
+func (e *ManifestError) Error() string {
+	return fmt.Sprintf("invalid manifest %s: %s", e.Path, strings.Join(e.Problems, "; "))
+}
+
+// LoadManifest reads the manifest at the root of repoPath. A repository
+// without a manifest returns nil and no error.
+func LoadManifest(repoPath string) (*Manifest, error) {
+	path := filepath.Join(repoPath, ManifestFile)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	return ParseManifest(path, data)
+}
+
+// ParseManifest decodes and validates manifest data. path is only used in
+// error messages.
+func ParseManifest(path string, data []byte) (*Manifest, error) {
+	manifest := &Manifest{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(manifest); err != nil && err != io.EOF {
+		var typeErr *yaml.TypeError
+		if errors.As(err, &typeErr) {
+			return nil, &ManifestError{Path: path, Problems: typeErr.Errors}
+		}
+		return nil, &ManifestError{Path: path, Problems: []string{err.Error()}}
+	}
+	if problems := manifest.Validate(); len(problems) > 0 {
+		return nil, &ManifestError{Path: path, Problems: problems}
+	}
+	return manifest, nil
+}
+
+// Validate checks the manifest against the schema and returns every problem.
+func (m *Manifest) Validate() []string {
+	var problems []string
+	if m.Version != 0 && m.Version != ManifestVersion {
+		problems = append(problems, fmt.Sprintf("unsupported version %d (want %d)", m.Version, ManifestVersion))
+	}
+
+	seen := make(map[string]bool)
+	for _, dep := range m.DependsOn {
+		switch {
+		case !validRepoName.MatchString(dep):
+			problems = append(problems, fmt.Sprintf("dependsOn: invalid repository name %q", dep))
+		case seen[dep]:
+			problems = append(problems, fmt.Sprintf("dependsOn: %q listed twice", dep))
+		}
+		seen[dep] = true
+	}
+	for _, group := range m.Groups {
+		if !validName.MatchString(group) {
+			problems = append(problems, fmt.Sprintf("groups: invalid group name %q", group))
+		}
+	}
+	for key := range m.Labels {
+		if strings.TrimSpace(key) == "" {
+			problems = append(problems, "labels: empty label name")
+		}
+	}
+
+	if m.Docker != nil {
+		for _, text := range m.Docker.Tags {
+			if _, err := parseTagTemplate(text); err != nil {
+				problems = append(problems, "docker.tags: "+err.Error())
+			}
+		}
+		problems = append(problems, validateProfile("docker", m.Docker.BuildProfile)...)
+		profiles := make([]string, 0, len(m.Docker.Profiles))
+		for name := range m.Docker.Profiles {
+			profiles = append(profiles, name)
+		}
+		sort.Strings(profiles)
+		for _, name := range profiles {
+			if !validName.MatchString(name) {
+				problems = append(problems, fmt.Sprintf("docker.profiles: invalid profile name %q", name))
+			}
+			merged := m.Docker.BuildProfile.merge(m.Docker.Profiles[name])
+			problems = append(problems, validateProfile("docker.profiles."+name, merged)...)
+		}
+	}
+
+	names := make([]string, 0, len(m.Tasks))
+	for name := range m.Tasks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		task := m.Tasks[name]
+		if !validName.MatchString(name) {
+			problems = append(problems, fmt.Sprintf("tasks: invalid task name %q", name))
+		}
+		if strings.TrimSpace(task.Run) == "" {
+			problems = append(problems, fmt.Sprintf("tasks.%s: run is required", name))
+		}
+		if task.Dir != "" && !insideRepo(task.Dir) {
+			problems = append(problems, fmt.Sprintf("tasks.%s.dir: %q must be a relative path inside the repository", name, task.Dir))
+		}
+	}
+	return problems
+}
+
+// validateProfile checks the build settings found at field.
+func validateProfile(field string, p BuildProfile) []string {
+	var problems []string
+	dockerfileOK := p.Dockerfile == "" || insideRepo(p.Dockerfile)
+	contextOK := p.Context == "" || insideRepo(p.Context)
+	if !dockerfileOK {
+		problems = append(problems, fmt.Sprintf("%s.dockerfile: %q must be a relative path inside the repository", field, p.Dockerfile))
+	}
+	if !contextOK {
+		problems = append(problems, fmt.Sprintf("%s.context: %q must be a relative path inside the repository", field, p.Context))
+	}
+	if dockerfileOK && contextOK {
+		if _, err := p.dockerfile(); err != nil {
+			problems = append(problems, fmt.Sprintf("%s.dockerfile: %v", field, err))
+		}
+	}
+	for key := range p.Args {
+		if strings.TrimSpace(key) == "" {
+			problems = append(problems, field+".args: empty argument name")
+		}
+	}
+	for key := range p.Labels {
+		if strings.TrimSpace(key) == "" {
+			problems = append(problems, field+".labels: empty label name")
+		}
+	}
+	return problems
+}
+
+// dockerfile returns the Dockerfile's path relative to the context.
+func (p BuildProfile) dockerfile() (string, error) {
+	dockerfile := p.Dockerfile
+	if dockerfile == "" {
+		dockerfile = filepath.Join(p.Context, "Dockerfile")
+	}
+	rel, err := filepath.Rel(filepath.Join(".", p.Context), dockerfile)
+	if err != nil || !insideRepo(rel) {
+		return "", fmt.Errorf("%q is outside the build context %q", dockerfile, p.Context)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// Profile returns the build settings of the named profile, or the default
+// ones for an empty name. A nil manifest has only the default profile.
+func (m *Manifest) Profile(name string) (BuildProfile, error) {
+	var defaults BuildProfile
+	if m != nil && m.Docker != nil {
+		defaults = m.Docker.BuildProfile
+	}
+	if name == "" {
+		return defaults, nil
+	}
+	if m == nil || m.Docker == nil {
+		return BuildProfile{}, fmt.Errorf("%w: %s", ErrUnknownProfile, name)
+	}
+	profile, ok := m.Docker.Profiles[name]
+	if !ok {
+		return BuildProfile{}, fmt.Errorf("%w: %s", ErrUnknownProfile, name)
+	}
+	return defaults.merge(profile), nil
+}
+
+// TagTemplates returns the templates for the tags of the repository's
+// images, DefaultTagTemplates unless the manifest sets docker.tags.
+func (m *Manifest) TagTemplates() []string {
+	if m == nil || m.Docker == nil || len(m.Docker.Tags) == 0 {
+		return DefaultTagTemplates
+	}
+	return m.Docker.Tags
+}
+
+// ImageRepository returns the name images of the repository called name
+// are built under: docker.image without its tag, or the repository name
+// made into a valid image name.
+func (m *Manifest) ImageRepository(name string) string {
+	if m == nil || m.Docker == nil || m.Docker.Image == "" {
+		return defaultImageRepository(name)
+	}
+	return imageName(m.Docker.Image)
+}
+
+var (
+	// invalidImageChars matches what may not appear in a component of an
+	// image repository name.
+	invalidImageChars = regexp.MustCompile(`[^a-z0-9._-]+`)
+	// imageSeparators matches runs of separators, of which only dashes may
+	// repeat.
+	imageSeparators = regexp.MustCompile(`[._-]{2,}`)
+)
+
+// defaultImageRepository turns a repository name such as "Work/API" into an
+// image repository name Docker accepts: lowercase, with every path segment
+// reduced to letters and digits joined by single separators.
+func defaultImageRepository(name string) string {
+	var segments []string
+	for _, segment := range strings.Split(strings.ToLower(name), "/") {
+		segment = invalidImageChars.ReplaceAllString(segment, "-")
+		segment = imageSeparators.ReplaceAllStringFunc(segment, func(run string) string {
+			if strings.Trim(run, "-") == "" {
+				return run
+			}
+			return "-"
+		})
+		if segment = strings.Trim(segment, "._-"); segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// ProfileNames returns the names of the manifest's build profiles, sorted.
+func (m *Manifest) ProfileNames() []string {
+	if m == nil || m.Docker == nil {
+		return nil
+	}
+	names := make([]string, 0, len(m.Docker.Profiles))
+	for name := range m.Docker.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// InGroup reports whether the manifest lists group.
+func (m *Manifest) InGroup(group string) bool {
+	if m == nil {
+		return false
+	}
+	for _, g := range m.Groups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+// insideRepo reports whether path is relative and stays inside the repository.
+func insideRepo(path string) bool {
+	if filepath.IsAbs(path) {
+		return false
+	}
+	clean := filepath.Clean(path)
+	return clean != ".." && !strings.HasPrefix(clean, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,218 @@
+// manifest_test.go
+package registry
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	git "github.com/go-git/go-git/v5"
+)
+
+func TestParseManifest(t *testing.T) {
+	data := []byte(`
+version: 1
+enabled: false
+dependsOn: [core, lib]
+groups: [backend]
+labels:
+  team: platform
+docker:
+  image: example/api
+  dockerfile: build/Dockerfile
+  args:
+    GO_VERSION: "1.20"
+tasks:
+  test: go test ./...
+  lint:
+    run: golangci-lint run
+    dir: cmd
+`)
+	manifest, err := ParseManifest(ManifestFile, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Enabled == nil || *manifest.Enabled {
+		t.Error("Expected enabled: false")
+	}
+	if !reflect.DeepEqual(manifest.DependsOn, []string{"core", "lib"}) {
+		t.Errorf("Unexpected dependsOn %v", manifest.DependsOn)
+	}
+	if !manifest.InGroup("backend") || manifest.Labels["team"] != "platform" {
+		t.Errorf("Unexpected groups/labels %v %v", manifest.Groups, manifest.Labels)
+	}
+	if manifest.Docker.Args["GO_VERSION"] != "1.20" {
+		t.Errorf("Unexpected docker args %v", manifest.Docker.Args)
+	}
+	if manifest.Tasks["test"].Run != "go test ./..." || manifest.Tasks["lint"].Dir != "cmd" {
+		t.Errorf("Unexpected tasks %+v", manifest.Tasks)
+	}
+}
+
+func TestParseManifestReportsEveryProblem(t *testing.T) {
+	data := []byte(`
+version: 2
+dependsOn: [core, core]
+docker:
+  dockerfile: ../Dockerfile
+tasks:
+  build: {}
+`)
+	_, err := ParseManifest(ManifestFile, data)
+	var manifestErr *ManifestError
+	if !errors.As(err, &manifestErr) {
+		t.Fatalf("Expected a ManifestError, got %v", err)
+	}
+	if len(manifestErr.Problems) != 4 {
+		t.Errorf("Expected 4 problems, got %q", manifestErr.Problems)
+	}
+
+	if _, err := ParseManifest(ManifestFile, []byte("dependsOn: [clients//portal]\n")); !errors.As(err, &manifestErr) {
+		t.Errorf("Expected an empty path segment to be rejected, got %v", err)
+	}
+	if _, err := ParseManifest(ManifestFile, []byte("dependson: [core]\n")); !errors.As(err, &manifestErr) {
+		t.Errorf("Expected unknown fields to be rejected, got %v", err)
+	}
+}
+
+func TestDiscoveryAppliesManifests(t *testing.T) {
+	projects := t.TempDir()
+	manifests := map[string]string{
+		"core":   "groups: [backend]\n",
+		"api":    "dependsOn: [core]\ngroups: [backend]\n",
+		"broken": "dependsOn: core\n",
+		"plain":  "",
+		// Repositories below the top level are named by their path.
+		"web":            "dependsOn: [clients/portal]\n",
+		"clients/portal": "",
+	}
+	for name, manifest := range manifests {
+		path := filepath.Join(projects, name)
+		if _, err := git.PlainInit(path, false); err != nil {
+			t.Fatal(err)
+		}
+		if manifest != "" {
+			if err := os.WriteFile(filepath.Join(path, ManifestFile), []byte(manifest), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	reg, err := NewRegistry(WithProjectsPath(projects), WithStatePath(""))
+	if err != nil {
+		t.Fatalf("NewRegistry failed despite a broken manifest: %v", err)
+	}
+	defer reg.RegistryActor.Mailbox.Close()
+
+	if got := len(reg.ListItems()); got != 6 {
+		t.Errorf("Expected all 6 repositories to be registered, got %d", got)
+	}
+	deps := reg.Coordinator.Dependencies()
+	if !reflect.DeepEqual(deps["api"], []string{"core"}) {
+		t.Errorf("Expected api to depend on core, got %v", deps["api"])
+	}
+	if !reflect.DeepEqual(deps["web"], []string{"clients/portal"}) {
+		t.Errorf("Expected web to depend on clients/portal, got %v", deps["web"])
+	}
+	if _, exists := deps["plain"]; exists {
+		t.Errorf("Expected no dependencies for a repository without a manifest, got %v", deps["plain"])
+	}
+	if errs := reg.ManifestErrors(); len(errs) != 1 || errs["broken"] == nil {
+		t.Errorf("Expected only 'broken' to report a manifest error, got %v", errs)
+	}
+	if group := reg.Group("backend"); !reflect.DeepEqual(group, []string{"api", "core"}) {
+		t.Errorf("Unexpected backend group %v", group)
+	}
+}
+
+func TestManifestImageRepository(t *testing.T) {
+	tests := []struct {
+		manifest *Manifest
+		name     string
+		want     string
+	}{
+		{nil, "api", "api"},
+		{nil, "Work/API", "work/api"},
+		{nil, "Clients/Acme Corp/web..app", "clients/acme-corp/web-app"},
+		{nil, "_private/--tools--", "private/tools"},
+		{&Manifest{Docker: &DockerManifest{Image: "example/api:1.0"}}, "Work/API", "example/api"},
+	}
+	for _, tt := range tests {
+		if got := tt.manifest.ImageRepository(tt.name); got != tt.want {
+			t.Errorf("ImageRepository(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestManifestBuildProfiles(t *testing.T) {
+	data := []byte(`
+docker:
+  target: runtime
+  args:
+    GO_VERSION: "1.20"
+  labels:
+    team: platform
+  profiles:
+    dev:
+      dockerfile: Dockerfile.dev
+      target: debug
+      args:
+        DEBUG: "1"
+      noCache: true
+    ci:
+      context: service
+      cacheFrom: [example/api:cache]
+`)
+	manifest, err := ParseManifest(ManifestFile, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := manifest.ProfileNames(); !reflect.DeepEqual(names, []string{"ci", "dev"}) {
+		t.Errorf("Unexpected profiles %v", names)
+	}
+
+	dev, err := manifest.Profile("dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := BuildProfile{
+		Dockerfile: "Dockerfile.dev",
+		Target:     "debug",
+		Args:       map[string]string{"GO_VERSION": "1.20", "DEBUG": "1"},
+		Labels:     map[string]string{"team": "platform"},
+		NoCache:    true,
+	}
+	if !reflect.DeepEqual(dev, want) {
+		t.Errorf("Expected %+v, got %+v", want, dev)
+	}
+	if _, ok := manifest.Docker.Args["DEBUG"]; ok {
+		t.Error("Expected merging a profile to leave the defaults alone")
+	}
+
+	ci, _ := manifest.Profile("ci")
+	if dockerfile, err := ci.dockerfile(); err != nil || dockerfile != "Dockerfile" || ci.Target != "runtime" {
+		t.Errorf("Expected the ci profile to build service/Dockerfile for runtime, got %q, %v, %+v", dockerfile, err, ci)
+	}
+	if _, err := manifest.Profile("prod"); !errors.Is(err, ErrUnknownProfile) {
+		t.Errorf("Expected ErrUnknownProfile, got %v", err)
+	}
+	if profile, err := (*Manifest)(nil).Profile(""); err != nil || !reflect.DeepEqual(profile, BuildProfile{}) {
+		t.Errorf("Expected empty default settings without a manifest, got %+v, %v", profile, err)
+	}
+
+	_, err = ParseManifest(ManifestFile, []byte(`
+docker:
+  profiles:
+    "bad name":
+      target: x
+    split:
+      context: service
+      dockerfile: Dockerfile
+`))
+	var manifestErr *ManifestError
+	if !errors.As(err, &manifestErr) || len(manifestErr.Problems) != 2 {
+		t.Errorf("Expected an invalid name and a Dockerfile outside the context, got %v", err)
+	}
+}
@@ -0,0 +1,219 @@
+// File: registry/metrics.go
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// MetricsNamespace prefixes every metric the registry exports.
+const MetricsNamespace = "middleware_registry"
+
+// Metrics collects Prometheus metrics for the registry's actors, scans,
+// builds and Docker operations. A nil *Metrics records nothing.
+type Metrics struct {
+	registry         *prometheus.Registry
+	messages         *prometheus.CounterVec
+	handlerDuration  *prometheus.HistogramVec
+	scanDuration     *prometheus.HistogramVec
+	builds           *prometheus.CounterVec
+	buildDuration    *prometheus.HistogramVec
+	dockerOperations *prometheus.CounterVec
+	dockerDuration   *prometheus.HistogramVec
+}
+
+// NewMetrics initializes a new Metrics with its own Prometheus registry.
+// mailboxes is called at every scrape to report mailbox depths; it may be nil.
+func NewMetrics(mailboxes func() map[string]*Mailbox) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		messages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: MetricsNamespace,
+			Name:      "messages_processed_total",
+			Help:      "Messages processed by each actor, by message type and result.",
+		}, []string{"actor", "message", "result"}),
+		handlerDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: MetricsNamespace,
+			Name:      "handler_duration_seconds",
+			Help:      "Time actors spend handling a message, by message type.",
+			Buckets:   prometheus.ExponentialBuckets(0.0005, 4, 10),
+		}, []string{"actor", "message"}),
+		scanDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: MetricsNamespace,
+			Name:      "scan_duration_seconds",
+			Help:      "Duration of repository discovery and scans.",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 4, 8),
+		}, []string{"operation"}),
+		builds: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: MetricsNamespace,
+			Name:      "builds_total",
+			Help:      "Docker image builds, by repository and result.",
+		}, []string{"repo", "result"}),
+		buildDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: MetricsNamespace,
+			Name:      "build_duration_seconds",
+			Help:      "Duration of Docker image builds, by repository.",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 10),
+		}, []string{"repo"}),
+		dockerOperations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: MetricsNamespace,
+			Name:      "docker_operations_total",
+			Help:      "Docker container operations, by operation and result.",
+		}, []string{"operation", "result"}),
+		dockerDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: MetricsNamespace,
+			Name:      "docker_operation_duration_seconds",
+			Help:      "Duration of Docker container operations, by operation.",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 4, 8),
+		}, []string{"operation"}),
+	}
+	m.registry.MustRegister(
+		m.messages,
+		m.handlerDuration,
+		m.scanDuration,
+		m.builds,
+		m.buildDuration,
+		m.dockerOperations,
+		m.dockerDuration,
+		&mailboxCollector{mailboxes: mailboxes},
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Serve exposes the metrics on addr under /metrics until ctx is cancelled.
+// It returns the address actually listened on, which helps when addr uses
+// port 0.
+func (m *Metrics) Serve(ctx context.Context, addr string) (string, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", fmt.Errorf("failed to listen for metrics on '%s': %w", addr, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m.Handler())
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Printf("Error serving metrics: %v\n", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	return listener.Addr().String(), nil
+}
+
+// ObserveMessage records that actor handled msg in the time since start.
+func (m *Metrics) ObserveMessage(actor string, msg Message, start time.Time, err error) {
+	if m == nil {
+		return
+	}
+	name := messageName(msg)
+	m.messages.WithLabelValues(actor, name, result(err)).Inc()
+	m.handlerDuration.WithLabelValues(actor, name).Observe(time.Since(start).Seconds())
+}
+
+// ObserveScan records the duration of a discovery or scan operation.
+func (m *Metrics) ObserveScan(operation string, start time.Time) {
+	if m == nil {
+		return
+	}
+	m.scanDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+// ObserveBuild records an image build of repo that started at start.
+func (m *Metrics) ObserveBuild(repo string, start time.Time, err error) {
+	if m == nil {
+		return
+	}
+	m.builds.WithLabelValues(repo, result(err)).Inc()
+	m.buildDuration.WithLabelValues(repo).Observe(time.Since(start).Seconds())
+}
+
+// ObserveDockerOperation records a container operation such as run, stop
+// or remove that started at start.
+func (m *Metrics) ObserveDockerOperation(operation string, start time.Time, err error) {
+	if m == nil {
+		return
+	}
+	m.dockerOperations.WithLabelValues(operation, result(err)).Inc()
+	m.dockerDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+// result turns an error into a metric label value.
+func result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
+
+// messageName returns the type name of msg without its package.
+func messageName(msg Message) string {
+	if msg == nil {
+		return "nil"
+	}
+	t := reflect.TypeOf(msg)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Name() == "" {
+		return t.String()
+	}
+	return t.Name()
+}
+
+// mailboxCollector reports the depth, capacity and drops of every actor
+// mailbox at scrape time.
+type mailboxCollector struct {
+	mailboxes func() map[string]*Mailbox
+}
+
+var (
+	mailboxDepthDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(MetricsNamespace, "mailbox", "depth"),
+		"Messages queued in an actor's mailbox.",
+		[]string{"actor"}, nil,
+	)
+	mailboxCapacityDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(MetricsNamespace, "mailbox", "capacity"),
+		"Capacity of an actor's mailbox.",
+		[]string{"actor"}, nil,
+	)
+	mailboxDroppedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(MetricsNamespace, "mailbox", "dropped_total"),
+		"Messages dropped from an actor's full mailbox.",
+		[]string{"actor"}, nil,
+	)
+)
+
+func (c *mailboxCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- mailboxDepthDesc
+	ch <- mailboxCapacityDesc
+	ch <- mailboxDroppedDesc
+}
+
+func (c *mailboxCollector) Collect(ch chan<- prometheus.Metric) {
+	if c.mailboxes == nil {
+		return
+	}
+	for actor, mailbox := range c.mailboxes() {
+		ch <- prometheus.MustNewConstMetric(mailboxDepthDesc, prometheus.GaugeValue, float64(mailbox.Len()), actor)
+		ch <- prometheus.MustNewConstMetric(mailboxCapacityDesc, prometheus.GaugeValue, float64(mailbox.Cap()), actor)
+		ch <- prometheus.MustNewConstMetric(mailboxDroppedDesc, prometheus.CounterValue, float64(mailbox.Dropped()), actor)
+	}
+}
@@ -0,0 +1,79 @@
+// metrics_test.go
+package registry
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func scrape(t *testing.T, handler http.Handler) string {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Scrape failed with status %d", recorder.Code)
+	}
+	return recorder.Body.String()
+}
+
+func TestRegistryMetrics(t *testing.T) {
+	projects := t.TempDir()
+	writeRepo(t, projects, "demo", nil)
+
+	reg, err := NewRegistry(WithProjectsPath(projects), WithStatePath(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	defer reg.Shutdown(ctx)
+
+	if _, err := reg.ToggleRepo(ctx, "demo"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reg.ToggleRepo(ctx, "missing"); err == nil {
+		t.Fatal("Expected toggling a missing repository to fail")
+	}
+
+	body := scrape(t, reg.Metrics.Handler())
+	for _, want := range []string{
+		`middleware_registry_messages_processed_total{actor="registry",message="ToggleRepo",result="success"} 1`,
+		`middleware_registry_messages_processed_total{actor="registry",message="ToggleRepo",result="error"} 1`,
+		`middleware_registry_messages_processed_total{actor="repo/demo",message="ToggleRepo",result="success"} 1`,
+		`middleware_registry_handler_duration_seconds_count{actor="registry",message="ToggleRepo"} 2`,
+		`middleware_registry_mailbox_capacity{actor="repo/demo"} 64`,
+		`middleware_registry_mailbox_depth{actor="coordinator"}`,
+		`middleware_registry_scan_duration_seconds_count{operation="discovery"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected metrics to contain %q", want)
+		}
+	}
+}
+
+func TestMetricsServe(t *testing.T) {
+	metrics := NewMetrics(nil)
+	metrics.ObserveDockerOperation("run", time.Now(), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	addr, err := metrics.Serve(ctx, "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.Get("http://" + addr + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if want := `middleware_registry_docker_operations_total{operation="run",result="success"} 1`; !strings.Contains(string(body), want) {
+		t.Errorf("Expected %q in:\n%s", want, body)
+	}
+}
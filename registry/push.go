@@ -0,0 +1,318 @@
+// File: registry/push.go
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	registrytypes "github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/pkg/jsonmessage"
+)
+
+var (
+	ErrPushFailed    = errors.New("image push failed")
+	ErrNothingToPush = errors.New("no registry-built images to push")
+	ErrNoPushTarget  = errors.New("no container registry to push to")
+)
+
+// PushPolicy controls how failed pushes are retried.
+type PushPolicy struct {
+	// Attempts is the number of times a reference is pushed before giving up.
+	Attempts int
+	// InitialBackoff is doubled after every failed attempt up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// DefaultPushPolicy returns the policy used when none is configured.
+func DefaultPushPolicy() PushPolicy {
+	return PushPolicy{
+		Attempts:       3,
+		InitialBackoff: time.Second,
+		MaxBackoff:     30 * time.Second,
+	}
+}
+
+// PushEventType identifies what happened during an image push.
+type PushEventType string
+
+const (
+	PushStarted  PushEventType = "started"  // An attempt to push a reference began
+	PushProgress PushEventType = "progress" // A layer's status changed
+	PushRetrying PushEventType = "retrying" // An attempt failed and will be retried
+	PushPushed   PushEventType = "pushed"   // A reference was pushed
+	PushFailed   PushEventType = "failed"   // A reference could not be pushed
+)
+
+// PushEvent is one thing that happened during an image push.
+type PushEvent struct {
+	Type    PushEventType
+	Repo    string
+	Ref     string        // Reference being pushed
+	Layer   string        // Layer ID, for PushProgress
+	Status  string        // Layer status or error message
+	Current int64         // Bytes of the layer pushed so far
+	Total   int64         // Size of the layer, when known
+	Attempt int           // Attempt number, from 1
+	Backoff time.Duration // Wait before the next attempt, for PushRetrying
+	Digest  string        // Manifest digest, for PushPushed
+}
+
+// Push is an image push in progress.
+type Push struct {
+	Repo    string
+	Refs    []string // References pushed, in order
+	events  chan PushEvent
+	done    chan struct{}
+	digests map[string]string
+	err     error
+}
+
+func newPush(repo string, refs []string) *Push {
+	return &Push{
+		Repo:    repo,
+		Refs:    refs,
+		events:  make(chan PushEvent, 16),
+		done:    make(chan struct{}),
+		digests: make(map[string]string),
+	}
+}
+
+// Events returns the push's events. The channel is closed when the push
+// ends.
+func (p *Push) Events() <-chan PushEvent {
+	return p.events
+}
+
+// Wait discards the events not received yet, waits for the push to end and
+// returns the digest pushed for each reference. References that failed are
+// missing, and their errors are joined.
+func (p *Push) Wait() (map[string]string, error) {
+	for range p.events {
+	}
+	<-p.done
+	return p.digests, p.err
+}
+
+// emit sends an event, dropping it once ctx is done so an abandoned push
+// does not block.
+func (p *Push) emit(ctx context.Context, event PushEvent) {
+	event.Repo = p.Repo
+	select {
+	case p.events <- event:
+	case <-ctx.Done():
+	}
+}
+
+// PushImage pushes the tags of the newest image the registry built for a
+// repository, or of every image it built when all is set, to the
+// configured container registry. Tags are pushed one after the other, each
+// retried according to Config.PushPolicy. The returned push must have its
+// events received, or Wait called, to make progress. It is cancelled with
+// ctx or when the registry shuts down.
+func (r *Registry) PushImage(ctx context.Context, repoName string, all bool) (*Push, error) {
+	history, err := r.builtImages(ctx, repoName)
+	if err != nil {
+		return nil, err
+	}
+	refs := pushRefs(history, all)
+	if len(refs) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNothingToPush, repoName)
+	}
+
+	ctx, cancel := withShutdown(ctx, r.ctx)
+	targets := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		target, err := pushReference(r.Config.PushTarget, ref)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		if target != ref {
+			if err := r.Docker.ImageTag(ctx, ref, target); err != nil {
+				cancel()
+				return nil, fmt.Errorf("failed to tag %s as %s: %w", ref, target, err)
+			}
+		}
+		targets = append(targets, target)
+	}
+
+	push := newPush(repoName, targets)
+	go func() {
+		defer cancel()
+		defer close(push.done)
+		defer close(push.events)
+		var errs []error
+		for _, ref := range targets {
+			start := time.Now()
+			digest, err := r.pushRef(ctx, push, ref)
+			r.Metrics.ObserveDockerOperation("push", start, err)
+			if err != nil {
+				push.emit(ctx, PushEvent{Type: PushFailed, Ref: ref, Status: err.Error()})
+				errs = append(errs, err)
+				if ctx.Err() != nil {
+					break
+				}
+				continue
+			}
+			push.digests[ref] = digest
+			push.emit(ctx, PushEvent{Type: PushPushed, Ref: ref, Digest: digest})
+		}
+		push.err = errors.Join(errs...)
+	}()
+	return push, nil
+}
+
+// pushRef pushes one reference, retrying failures that may be transient.
+func (r *Registry) pushRef(ctx context.Context, push *Push, ref string) (string, error) {
+	credentials, err := r.pushCredentials(referenceHost(ref))
+	if err != nil {
+		return "", err
+	}
+	auth, err := registrytypes.EncodeAuthConfig(credentials)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode credentials: %w", err)
+	}
+
+	var digest string
+	err = retry(ctx, r.Config.PushPolicy, func(attempt int) error {
+		push.emit(ctx, PushEvent{Type: PushStarted, Ref: ref, Attempt: attempt})
+		output, err := r.Docker.ImagePush(ctx, ref, types.ImagePushOptions{RegistryAuth: auth})
+		if err != nil {
+			return fmt.Errorf("failed to push %s: %w", ref, err)
+		}
+		defer output.Close()
+		digest, err = parsePushOutput(output, func(event PushEvent) {
+			event.Ref, event.Attempt = ref, attempt
+			push.emit(ctx, event)
+		})
+		return err
+	}, func(attempt int, err error, backoff time.Duration) {
+		push.emit(ctx, PushEvent{Type: PushRetrying, Ref: ref, Status: err.Error(), Attempt: attempt, Backoff: backoff})
+	})
+	return digest, err
+}
+
+// retry calls attempt until it succeeds, fails in a way retrying cannot
+// fix, or policy.Attempts calls have been made. retrying is told about each
+// failure that will be retried and the backoff before the next attempt.
+func retry(ctx context.Context, policy PushPolicy, attempt func(n int) error, retrying func(n int, err error, backoff time.Duration)) error {
+	backoff := policy.InitialBackoff
+	for n := 1; ; n++ {
+		err := attempt(n)
+		if err == nil || n >= policy.Attempts || !retryablePushError(err) || ctx.Err() != nil {
+			return err
+		}
+		retrying(n, err, backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return err
+		}
+		if backoff *= 2; backoff > policy.MaxBackoff {
+			backoff = policy.MaxBackoff
+		}
+	}
+}
+
+// retryablePushError reports whether pushing again may succeed after err.
+// Refused credentials and missing images fail the same way every time.
+func retryablePushError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	message := strings.ToLower(err.Error())
+	for _, permanent := range []string{"unauthorized", "denied", "authentication required", "does not exist", "no such image", "invalid reference"} {
+		if strings.Contains(message, permanent) {
+			return false
+		}
+	}
+	return true
+}
+
+// pushRefs returns the registry-built tags to push, newest image first:
+// those of the newest image, or of every image when all is set. Tags since
+// moved to another image are left out.
+func pushRefs(history []BuiltImage, all bool) []string {
+	var refs []string
+	seen := make(map[string]bool)
+	for i, image := range history {
+		if i > 0 && !all {
+			break
+		}
+		built := make(map[string]bool, len(image.Tags))
+		for _, tag := range image.Tags {
+			built[tag] = true
+		}
+		for _, ref := range image.Current {
+			if built[ref] && !seen[ref] {
+				seen[ref] = true
+				refs = append(refs, ref)
+			}
+		}
+	}
+	return refs
+}
+
+// pushReference returns the reference ref is pushed as. With a target,
+// such as "localhost:5000" or "ghcr.io/team", ref is moved under it;
+// otherwise ref must already name a registry or a Docker Hub namespace.
+func pushReference(target, ref string) (string, error) {
+	target = strings.TrimSuffix(target, "/")
+	if target == "" {
+		if referenceHost(ref) == dockerHubHost && !strings.Contains(ref, "/") {
+			return "", fmt.Errorf("%w: set a push target for %s", ErrNoPushTarget, ref)
+		}
+		return ref, nil
+	}
+	name := ref
+	if first, rest, found := strings.Cut(ref, "/"); found && (referenceHost(ref) != dockerHubHost || first == dockerHubHost) {
+		name = rest
+	}
+	return target + "/" + name, nil
+}
+
+// parsePushOutput reads the JSON messages of a Docker push, passing layer
+// progress to emit, and returns the digest pushed. An error in the output,
+// or output ending without a digest, is returned as an error.
+func parsePushOutput(output io.Reader, emit func(PushEvent)) (string, error) {
+	var digest string
+	decoder := json.NewDecoder(output)
+	for {
+		var msg jsonmessage.JSONMessage
+		err := decoder.Decode(&msg)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read push output: %w", err)
+		}
+		switch {
+		case msg.Error != nil:
+			return "", fmt.Errorf("%w: %s", ErrPushFailed, msg.Error.Message)
+		case msg.ErrorMessage != "":
+			return "", fmt.Errorf("%w: %s", ErrPushFailed, msg.ErrorMessage)
+		case msg.Aux != nil:
+			var aux struct{ Digest string }
+			if err := json.Unmarshal(*msg.Aux, &aux); err == nil && aux.Digest != "" {
+				digest = aux.Digest
+			}
+		case msg.Status != "":
+			event := PushEvent{Type: PushProgress, Layer: msg.ID, Status: msg.Status}
+			if msg.Progress != nil {
+				event.Current, event.Total = msg.Progress.Current, msg.Progress.Total
+			}
+			emit(event)
+		}
+	}
+	if digest == "" {
+		return "", fmt.Errorf("%w: the push ended without a digest", ErrPushFailed)
+	}
+	return digest, nil
+}
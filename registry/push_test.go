@@ -0,0 +1,222 @@
+// push_test.go
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+)
+
+func TestParsePushOutput(t *testing.T) {
+	output := strings.Join([]string{
+		`{"status":"The push refers to repository [localhost:5000/api]"}`,
+		`{"status":"Preparing","progressDetail":{},"id":"5f70bf18a086"}`,
+		`{"status":"Pushing","progressDetail":{"current":512,"total":1024},"progress":"[==>  ]","id":"5f70bf18a086"}`,
+		`{"status":"Pushed","progressDetail":{},"id":"5f70bf18a086"}`,
+		`{"status":"latest: digest: sha256:abcd size: 528"}`,
+		`{"progressDetail":{},"aux":{"Tag":"latest","Digest":"sha256:abcd","Size":528}}`,
+	}, "\n")
+	var events []PushEvent
+	digest, err := parsePushOutput(strings.NewReader(output), func(e PushEvent) {
+		events = append(events, e)
+	})
+	if err != nil || digest != "sha256:abcd" {
+		t.Fatalf("Expected digest sha256:abcd, got %q, %v", digest, err)
+	}
+	if len(events) != 5 {
+		t.Fatalf("Expected 5 progress events, got %+v", events)
+	}
+	if e := events[2]; e.Layer != "5f70bf18a086" || e.Status != "Pushing" || e.Current != 512 || e.Total != 1024 {
+		t.Errorf("Unexpected layer progress %+v", e)
+	}
+
+	failed := `{"errorDetail":{"message":"unauthorized: authentication required"},"error":"unauthorized: authentication required"}`
+	if _, err := parsePushOutput(strings.NewReader(failed), func(PushEvent) {}); !errors.Is(err, ErrPushFailed) || retryablePushError(err) {
+		t.Errorf("Expected a permanent ErrPushFailed, got %v", err)
+	}
+	if _, err := parsePushOutput(strings.NewReader(`{"status":"Preparing","id":"x"}`), func(PushEvent) {}); !errors.Is(err, ErrPushFailed) {
+		t.Errorf("Expected output without a digest to fail, got %v", err)
+	}
+}
+
+func TestPushReference(t *testing.T) {
+	tests := []struct {
+		target, ref, want string
+		err               error
+	}{
+		{"localhost:5000", "api:1a2b3c4", "localhost:5000/api:1a2b3c4", nil},
+		{"ghcr.io/team/", "example/api:latest", "ghcr.io/team/example/api:latest", nil},
+		{"localhost:5000", "registry.example.com/api:v1", "localhost:5000/api:v1", nil},
+		{"", "example/api:latest", "example/api:latest", nil},
+		{"", "registry.example.com/api:v1", "registry.example.com/api:v1", nil},
+		{"", "api:latest", "", ErrNoPushTarget},
+	}
+	for _, tt := range tests {
+		got, err := pushReference(tt.target, tt.ref)
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("pushReference(%q, %q) = %q, %v; want %q, %v", tt.target, tt.ref, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestPushRefs(t *testing.T) {
+	history := []BuiltImage{
+		{Tags: []string{"api:2b3c4d5", "api:main", "api:latest"}, Current: []string{"api:2b3c4d5", "api:main", "api:latest", "localhost:5000/api:main"}},
+		{Tags: []string{"api:1a2b3c4", "api:main", "api:latest"}, Current: []string{"api:1a2b3c4"}},
+	}
+	if refs := pushRefs(history, false); !reflect.DeepEqual(refs, []string{"api:2b3c4d5", "api:main", "api:latest"}) {
+		t.Errorf("Unexpected refs of the newest image %v", refs)
+	}
+	if refs := pushRefs(history, true); !reflect.DeepEqual(refs, []string{"api:2b3c4d5", "api:main", "api:latest", "api:1a2b3c4"}) {
+		t.Errorf("Unexpected refs of every image %v", refs)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	policy := PushPolicy{Attempts: 4, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+	var backoffs []time.Duration
+	calls := 0
+	err := retry(context.Background(), policy, func(n int) error {
+		calls++
+		if n < 4 {
+			return errors.New("connection reset by peer")
+		}
+		return nil
+	}, func(n int, err error, backoff time.Duration) {
+		backoffs = append(backoffs, backoff)
+	})
+	if err != nil || calls != 4 {
+		t.Errorf("Expected success on the fourth attempt, got %v after %d", err, calls)
+	}
+	if want := []time.Duration{time.Millisecond, 2 * time.Millisecond, 2 * time.Millisecond}; !reflect.DeepEqual(backoffs, want) {
+		t.Errorf("Expected backoffs %v, got %v", want, backoffs)
+	}
+
+	calls = 0
+	err = retry(context.Background(), policy, func(int) error {
+		calls++
+		return errors.New("denied: requested access to the resource is denied")
+	}, func(int, error, time.Duration) {})
+	if err == nil || calls != 1 {
+		t.Errorf("Expected a permanent error to stop after one attempt, got %v after %d", err, calls)
+	}
+}
+
+func TestDockerCredentials(t *testing.T) {
+	dir := t.TempDir()
+	config := map[string]interface{}{
+		"auths": map[string]interface{}{
+			"https://index.docker.io/v1/": map[string]string{"auth": base64.StdEncoding.EncodeToString([]byte("hub:secret"))},
+			"localhost:5000":              map[string]string{"username": "local", "password": "pw"},
+		},
+		"credHelpers": map[string]string{"ghcr.io": "test"},
+	}
+	data, _ := json.Marshal(config)
+	path := filepath.Join(dir, "config.json")
+	os.WriteFile(path, data, 0600)
+
+	// A fake credential helper on PATH.
+	helper := "#!/bin/sh\nread url\necho \"{\\\"ServerURL\\\":\\\"$url\\\",\\\"Username\\\":\\\"<token>\\\",\\\"Secret\\\":\\\"tok\\\"}\"\n"
+	os.WriteFile(filepath.Join(dir, "docker-credential-test"), []byte(helper), 0755)
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	tests := []struct {
+		host, username, password, token string
+	}{
+		{"docker.io", "hub", "secret", ""},
+		{"localhost:5000", "local", "pw", ""},
+		{"ghcr.io", "", "", "tok"},
+		{"quay.io", "", "", ""},
+	}
+	for _, tt := range tests {
+		credentials, err := dockerCredentials(path, tt.host)
+		if err != nil {
+			t.Errorf("%s: %v", tt.host, err)
+			continue
+		}
+		if credentials.Username != tt.username || credentials.Password != tt.password || credentials.IdentityToken != tt.token {
+			t.Errorf("%s: unexpected credentials %+v", tt.host, credentials)
+		}
+	}
+}
+
+// TestPushToRegistry builds an image and pushes it to the registry:2
+// container named by REGISTRY_TEST_PUSH_TARGET, for example
+// "localhost:5000" after `docker run -d -p 5000:5000 registry:2`.
+func TestPushToRegistry(t *testing.T) {
+	target := os.Getenv("REGISTRY_TEST_PUSH_TARGET")
+	if target == "" {
+		t.Skip("REGISTRY_TEST_PUSH_TARGET is not set")
+	}
+
+	projects := t.TempDir()
+	path := filepath.Join(projects, "pushtest")
+	repo, err := git.PlainInit(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFile(t, repo, "Dockerfile", "FROM scratch\nCOPY a.txt /a.txt\n")
+	commitFile(t, repo, "a.txt", fmt.Sprintf("built at %s\n", time.Now()))
+
+	opts := []OptsFunc{WithProjectsPath(projects), WithStatePath(""), WithPushTarget(target)}
+	if host := os.Getenv("DOCKER_HOST"); host != "" {
+		opts = append(opts, WithDockerHost(host))
+	}
+	reg, err := NewRegistry(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reg.RegistryActor.Mailbox.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	build, err := reg.BuildImage(ctx, "pushtest", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := build.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	push, err := reg.PushImage(ctx, "pushtest", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pushed []string
+	for event := range push.Events() {
+		if event.Type == PushPushed {
+			pushed = append(pushed, event.Ref)
+		}
+	}
+	digests, err := push.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pushed) != len(build.Tags) || len(digests) != len(build.Tags) {
+		t.Errorf("Expected %d tags to be pushed, got %v", len(build.Tags), pushed)
+	}
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/v2/pushtest/tags/list", target))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var list struct{ Tags []string }
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(strings.Join(list.Tags, ","), "latest") {
+		t.Errorf("Expected the registry to list latest, got %v", list.Tags)
+	}
+}
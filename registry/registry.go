@@ -2,9 +2,11 @@
 package registry
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
@@ -19,11 +21,19 @@ type RegistryItem struct {
 	Type          string
 	Status        string
 	Path          string
+	Parent        string // Repository this one is a child of, if any
+	URL           string // Remote the repository was cloned from, if any
+	InitErr       error  // Why initialization, such as a clone, failed
 	CreatedAt     time.Time
 	LastUpdated   time.Time
 	Enabled       bool
 	GitRepo       *git.Repository
+	GitStatus     *GitStatus // Nil until the status has been read
+	GitErr        error
 	HasDockerfile bool
+	Manifest      *Manifest
+	ManifestErr   error
+	Hooks         []HookStatus // Filled in by Item
 }
 
 // Registry manages a collection of RepoActors and the RegistryActor.
@@ -32,14 +42,35 @@ type Registry struct {
 	Coordinator    *CoordinatorActor
 	Docker         *client.Client
 	Config         *Config
+	Metrics        *Metrics
+	store          *StateStore
+	journal        *Journal
 	wg             *sync.WaitGroup
+	ctx            context.Context // Cancelled by Shutdown
+	cancel         context.CancelFunc
+	shutdownOnce   sync.Once
+	shutdownErr    error
+	watcher        *WatcherActor
+	watchMu        sync.Mutex
 }
 
 // Config holds the configuration settings for the Registry.
 type Config struct {
-    ProjectsPath string
-    DockerHost   string
-    LogLevel     string
+    ProjectsPath  string
+    DockerHost    string
+    LogLevel      string
+    StatePath     string
+    RestartPolicy RestartPolicy
+    MailboxSize   int
+    Overflow      OverflowPolicy
+    MetricsAddr   string
+    Discovery     DiscoveryOptions
+    Watch         bool
+    WatchDebounce time.Duration
+    PushTarget    string // Registry, and optionally namespace, images are pushed to
+    PushUsername  string
+    PushPassword  string
+    PushPolicy    PushPolicy
 }
 
 // OptsFunc defines the function signature for configuration options.
@@ -66,13 +97,125 @@ func WithLogLevel(level string) OptsFunc {
     }
 }
 
+// WithStatePath sets the file the registry state is persisted to. An empty
+// path disables persistence.
+func WithStatePath(path string) OptsFunc {
+    return func(c *Config) {
+        c.StatePath = path
+    }
+}
+
+// WithRestartPolicy sets how crashed repository actors are restarted.
+func WithRestartPolicy(policy RestartPolicy) OptsFunc {
+    return func(c *Config) {
+        c.RestartPolicy = policy
+    }
+}
+
+// WithMailbox sets the capacity and overflow policy of actor mailboxes.
+func WithMailbox(capacity int, overflow OverflowPolicy) OptsFunc {
+    return func(c *Config) {
+        c.MailboxSize = capacity
+        c.Overflow = overflow
+    }
+}
+
+// WithMetricsAddr serves Prometheus metrics on addr under /metrics. Metrics
+// are collected either way; an empty addr (the default) serves nothing.
+func WithMetricsAddr(addr string) OptsFunc {
+    return func(c *Config) {
+        c.MetricsAddr = addr
+    }
+}
+
+// WithDiscoveryDepth limits discovery to depth directory levels below the
+// projects path. Zero removes the limit.
+func WithDiscoveryDepth(depth int) OptsFunc {
+    return func(c *Config) {
+        c.Discovery.MaxDepth = depth
+    }
+}
+
+// WithDiscoveryInclude only registers repositories matching one of globs.
+func WithDiscoveryInclude(globs ...string) OptsFunc {
+    return func(c *Config) {
+        c.Discovery.Include = globs
+    }
+}
+
+// WithDiscoveryExclude skips directories matching one of globs during
+// discovery.
+func WithDiscoveryExclude(globs ...string) OptsFunc {
+    return func(c *Config) {
+        c.Discovery.Exclude = globs
+    }
+}
+
+// WithNestedDiscovery makes discovery look inside the repositories it finds
+// for nested repositories.
+func WithNestedDiscovery(nested bool) OptsFunc {
+    return func(c *Config) {
+        c.Discovery.Nested = nested
+    }
+}
+
+// WithDiscoveryProgress reports discovery progress to fn.
+func WithDiscoveryProgress(fn func(DiscoveryProgress)) OptsFunc {
+    return func(c *Config) {
+        c.Discovery.Progress = fn
+    }
+}
+
+// WithWatch keeps the registry in sync with the projects directory, acting
+// on file system changes once none has arrived for debounce. A zero debounce
+// uses DefaultWatchDebounce.
+func WithWatch(debounce time.Duration) OptsFunc {
+    return func(c *Config) {
+        c.Watch = true
+        c.WatchDebounce = debounce
+    }
+}
+
+// WithPushTarget sets the container registry images are pushed to, such as
+// "localhost:5000" or "ghcr.io/team".
+func WithPushTarget(target string) OptsFunc {
+    return func(c *Config) {
+        c.PushTarget = target
+    }
+}
+
+// WithPushCredentials sets the credentials used to push images instead of
+// those in the Docker config.
+func WithPushCredentials(username, password string) OptsFunc {
+    return func(c *Config) {
+        c.PushUsername = username
+        c.PushPassword = password
+    }
+}
+
+// WithPushPolicy sets how failed pushes are retried.
+func WithPushPolicy(policy PushPolicy) OptsFunc {
+    return func(c *Config) {
+        c.PushPolicy = policy
+    }
+}
+
 // NewRegistry initializes and returns a new Registry instance.
-func NewRegistry(opts ...OptsFunc) (*Registry, error) {
+func NewRegistry(opts ...OptsFunc) (_ *Registry, err error) {
     // Set default configuration values.
     config := &Config{
-        ProjectsPath: "/home/cdaprod/Projects",
-        DockerHost:   "unix:///var/run/docker.sock",
-        LogLevel:     "info",
+        ProjectsPath:  "/home/cdaprod/Projects",
+        DockerHost:    "unix:///var/run/docker.sock",
+        LogLevel:      "info",
+        StatePath:     DefaultStatePath(),
+        RestartPolicy: DefaultRestartPolicy(),
+        PushPolicy:    DefaultPushPolicy(),
+        MailboxSize:   DefaultMailboxCapacity,
+        Overflow:      Block,
+        Discovery: DiscoveryOptions{
+            MaxDepth: DefaultDiscoveryDepth,
+            Exclude:  []string{"node_modules"},
+        },
     }
 
     // Apply options.
@@ -87,10 +230,15 @@ func NewRegistry(opts ...OptsFunc) (*Registry, error) {
     }
 
     wg := &sync.WaitGroup{}
+    ctx, cancel := context.WithCancel(context.Background())
 
     // Initialize RegistryActor and Coordinator.
     registryActor := NewRegistryActor(wg)
+    registryActor.SetRestartPolicy(config.RestartPolicy)
+    registryActor.SetMailboxOptions(config.MailboxSize, config.Overflow)
+    registryActor.SetContext(ctx)
     coordinator := NewCoordinatorActor(wg, registryActor)
+    coordinator.SetContext(ctx)
 
     reg := &Registry{
         RegistryActor: registryActor,
@@ -98,73 +246,393 @@ func NewRegistry(opts ...OptsFunc) (*Registry, error) {
         Docker:        docker,
         Config:        config,
         wg:            wg,
+        ctx:           ctx,
+        cancel:        cancel,
+    }
+
+    // Release whatever was started if a later step fails.
+    started := false
+    defer func() {
+        if err == nil {
+            return
+        }
+        if started {
+            shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), DefaultAskTimeout)
+            defer cancelShutdown()
+            reg.Shutdown(shutdownCtx)
+        }
+        cancel()
+        docker.Close()
+    }()
+
+    reg.Metrics = NewMetrics(reg.mailboxes)
+    registryActor.SetMetrics(reg.Metrics)
+    coordinator.SetMetrics(reg.Metrics)
+
+    // Restore the state saved by previous runs, replaying journal entries
+    // that did not make it into the snapshot.
+    if config.StatePath != "" {
+        reg.store = NewStateStore(config.StatePath)
+        state, err := reg.store.Load()
+        if err != nil {
+            return nil, fmt.Errorf("failed to load registry state: %w", err)
+        }
+        reg.journal, err = OpenJournal(filepath.Join(filepath.Dir(config.StatePath), "journal.log"))
+        if err != nil {
+            return nil, fmt.Errorf("failed to open registry journal: %w", err)
+        }
+        entries, err := reg.journal.Entries(state.JournalSeq)
+        if err != nil {
+            return nil, fmt.Errorf("failed to replay registry journal: %w", err)
+        }
+        for _, entry := range entries {
+            state.Apply(entry)
+        }
+        reg.RegistryActor.SetJournal(reg.journal)
+        reg.RegistryActor.Restore(state)
+        for repo, deps := range state.Dependencies {
+            edges, recorded := state.Edges[repo]
+            if !recorded {
+                edges = edgesFrom(repo, deps, SourceManual)
+            }
+            if err := reg.Coordinator.SetEdges(repo, edges); err != nil {
+                fmt.Printf("Ignoring saved dependencies of '%s': %v\n", repo, err)
+            }
+        }
+        reg.RegistryActor.OnChange(func() {
+            if err := reg.saveState(); err != nil {
+                fmt.Printf("Error saving registry state: %v\n", err)
+            }
+        })
+    }
+
+    // Start RegistryActor and Coordinator before discovery sends them work.
+    reg.RegistryActor.Start()
+    reg.Coordinator.Start()
+    started = true
+
+    if config.MetricsAddr != "" {
+        addr, err := reg.Metrics.Serve(ctx, config.MetricsAddr)
+        if err != nil {
+            return nil, err
+        }
+        fmt.Printf("Serving metrics on http://%s/metrics\n", addr)
     }
 
     // Auto-discover repositories.
+    start := time.Now()
     if err := reg.discoverRepositories(); err != nil {
         return nil, fmt.Errorf("failed to discover repositories: %w", err)
     }
+    reg.Metrics.ObserveScan("discovery", start)
+    if err := reg.saveState(); err != nil {
+        return nil, fmt.Errorf("failed to save registry state: %w", err)
+    }
 
-    // Start RegistryActor and Coordinator.
-    reg.RegistryActor.Start()
-    reg.Coordinator.Start()
+    if config.Watch {
+        if err := reg.Watch(config.WatchDebounce); err != nil {
+            return nil, err
+        }
+    }
 
     return reg, nil
 }
 
 // discoverRepositories scans the ProjectsPath for Git repositories and adds them to the registry.
 func (r *Registry) discoverRepositories() error {
-    entries, err := os.ReadDir(r.Config.ProjectsPath)
+    discovered, err := Discover(r.Config.ProjectsPath, r.Config.Discovery)
     if err != nil {
-        return fmt.Errorf("failed to read projects directory '%s': %w", r.Config.ProjectsPath, err)
+        return err
     }
 
-	for _, entry := range entries {
-		if !entry.IsDir() {
+	for _, repo := range discovered {
+		// Repositories restored from the state file keep their flags, and
+		// their names.
+		if r.RegistryActor.registeredAt(repo.Path) != nil {
+			continue
+		}
+		if existing, err := r.RegistryActor.lookup(repo.Name); err == nil {
+			fmt.Printf("Repository at '%s' not added: the name '%s' is taken by '%s'.\n", repo.Path, repo.Name, existing.Path)
 			continue
 		}
 
-		// Use entry.Info() to retrieve os.FileInfo
-		info, err := entry.Info()
+		// Add the repository to the RegistryActor
+		ctx, cancel := context.WithTimeout(context.Background(), DefaultAskTimeout)
+		_, err := Ask(ctx, r.RegistryActor.Mailbox, AddRepo{Name: repo.Name, Path: repo.Path, Kind: repo.Kind, Parent: repo.Parent})
+		cancel()
 		if err != nil {
-			return fmt.Errorf("failed to retrieve file info: %w", err)
+			return fmt.Errorf("failed to add repository '%s': %w", repo.Name, err)
 		}
 
-		projectPath := filepath.Join(r.Config.ProjectsPath, entry.Name())
+		fmt.Printf("Repository '%s' discovered and added to the registry.\n", repo.Name)
+	}
 
-		// Check if it's a git repository
-		repo, err := git.PlainOpen(projectPath)
-		isGitRepo := err == nil
+	r.resolveDependencies()
+	return nil
+}
 
-		// Check for Dockerfile
-		_, dockerfileErr := os.Stat(filepath.Join(projectPath, "Dockerfile"))
-		hasDockerfile := dockerfileErr == nil
+// resolveDependencies reloads every repository manifest and rebuilds the
+// Coordinator's edges. A manifest that declares dependsOn overrides what is
+// inferred from go.mod, package.json and the Dockerfile; dependencies added
+// by hand are left alone. A bad manifest is reported for its own repository
+// only.
+func (r *Registry) resolveDependencies() map[string]error {
+	manifests, errs := r.RegistryActor.ReloadManifests()
+	paths := make(map[string]string)
+	for _, item := range r.RegistryActor.ListItems() {
+		paths[item.Name] = item.Path
+	}
+	inferred := InferDependencies(paths, manifests)
 
-		if isGitRepo {
-			// Add the repository to the RegistryActor
-			r.RegistryActor.MsgChan <- AddRepo{
-				Name: entry.Name(),
-				Path: projectPath,
-			}
+	names := make([]string, 0, len(paths))
+	for name := range paths {
+		names = append(names, name)
+	}
+	sort.Strings(names)
 
-			// Optionally add to the Coordinator for dependency management
-			// Example: repoName depends on "base-repo"
-			if entry.Name() != "base-repo" {
-				r.Coordinator.AddDependency(entry.Name(), []string{"base-repo"})
-			}
+	for _, name := range names {
+		if r.Coordinator.manual(name) {
+			continue
+		}
+		edges := inferred[name]
+		if manifest := manifests[name]; manifest != nil && manifest.DependsOn != nil {
+			edges = edgesFrom(name, manifest.DependsOn, SourceManifest)
+		}
+		if err := r.Coordinator.SetEdges(name, edges); err != nil {
+			errs[name] = fmt.Errorf("failed to apply dependencies of '%s': %w", name, err)
+		}
+	}
+	failed := make([]string, 0, len(errs))
+	for name := range errs {
+		failed = append(failed, name)
+	}
+	sort.Strings(failed)
+	for _, name := range failed {
+		fmt.Printf("Manifest error in repository '%s': %v\n", name, errs[name])
+	}
+	return errs
+}
 
-			fmt.Printf("Repository '%s' discovered and added to the registry.\n", entry.Name())
+// ManifestErrors returns the manifest problems of every repository that has one.
+func (r *Registry) ManifestErrors() map[string]error {
+	errs := make(map[string]error)
+	for _, item := range r.RegistryActor.ListItems() {
+		if item.ManifestErr != nil {
+			errs[item.Name] = item.ManifestErr
 		}
 	}
+	return errs
+}
 
+// Group returns the names of the repositories whose manifest lists group,
+// sorted by name.
+func (r *Registry) Group(group string) []string {
+	var names []string
+	for _, item := range r.RegistryActor.ListItems() {
+		if item.Manifest.InGroup(group) {
+			names = append(names, item.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// saveState writes the current repositories and dependency graph to the
+// state file.
+func (r *Registry) saveState() error {
+	if r.store == nil {
+		return nil
+	}
+	state := NewState()
+	// Read the sequence first: entries appended while the snapshot is taken
+	// are replayed again on the next start, which is harmless.
+	state.JournalSeq = r.journal.Seq()
+	state.Repos = r.RegistryActor.States()
+	state.Dependencies = r.Coordinator.Dependencies()
+	state.Edges = r.Coordinator.Edges()
+	if err := r.store.Save(state); err != nil {
+		return err
+	}
+	if r.journal.NeedsCompaction() {
+		return r.journal.Compact(state.JournalSeq)
+	}
 	return nil
 }
 
+// mailboxes returns every actor mailbox for the metrics collector.
+func (r *Registry) mailboxes() map[string]*Mailbox {
+	mailboxes := r.RegistryActor.Mailboxes()
+	mailboxes["coordinator"] = r.Coordinator.Mailbox
+	return mailboxes
+}
+
+// History returns the journaled changes for repo, oldest first. An empty
+// repo returns the history of every repository.
+func (r *Registry) History(repo string) ([]JournalEntry, error) {
+	if r.journal == nil {
+		return nil, fmt.Errorf("registry journal is disabled")
+	}
+	return r.journal.History(repo)
+}
+
+// Subscribe registers fn for registry events such as actor crashes and
+// restarts. The returned function cancels the subscription.
+func (r *Registry) Subscribe(fn func(Event)) func() {
+	return r.RegistryActor.Events.Subscribe(fn)
+}
+
 // ListItems returns a list of all RegistryItems (repositories).
 func (r *Registry) ListItems() []RegistryItem {
 	return r.RegistryActor.ListItems()
 }
 
+// Item returns the registry entry of a single repository.
+func (r *Registry) Item(name string) (RegistryItem, error) {
+	repo, err := r.RegistryActor.lookup(name)
+	if err != nil {
+		return RegistryItem{}, err
+	}
+	item := repo.item()
+	item.Hooks, _ = repo.hookStatuses()
+	return item, nil
+}
+
+// AddRepo registers a repository and returns its registry entry. source is
+// either a local path or a Git URL; a URL is cloned into ProjectsPath/name
+// and AddRepo waits for the clone to finish.
+func (r *Registry) AddRepo(ctx context.Context, name, source string, opts ...CloneOptsFunc) (RegistryItem, error) {
+	msg := AddRepo{Name: name, Path: source}
+	if IsRemoteURL(source) {
+		msg.Path = filepath.Join(r.Config.ProjectsPath, name)
+		msg.URL = source
+		for _, opt := range opts {
+			opt(&msg.Clone)
+		}
+	}
+	result, err := Ask(ctx, r.RegistryActor.Mailbox, msg)
+	if err != nil {
+		return RegistryItem{}, err
+	}
+	if msg.URL == "" {
+		return result.(RegistryItem), nil
+	}
+
+	// The clone runs as the repository's InitRepo; wait for it.
+	repo, err := r.RegistryActor.lookup(name)
+	if err != nil {
+		return result.(RegistryItem), err
+	}
+	if _, err := Ask(ctx, repo.Mailbox, Sync{}); err != nil {
+		return result.(RegistryItem), err
+	}
+	item := repo.item()
+	return item, item.InitErr
+}
+
+// RemoveRepo removes a repository from the registry.
+func (r *Registry) RemoveRepo(ctx context.Context, name string) error {
+	_, err := Ask(ctx, r.RegistryActor.Mailbox, RemoveRepo{Name: name})
+	return err
+}
+
+// ScanRepositories scans the projects directory for repositories with the
+// configured discovery options and waits until every repository found has
+// been added. It returns how many were found. Repositories whose name is
+// taken by one at another path are skipped and reported in an error wrapping
+// ErrRepoExists once the others are added.
+func (r *Registry) ScanRepositories(ctx context.Context) (int, error) {
+	return r.Scan(ctx, r.Config.Discovery)
+}
+
+// Scan is ScanRepositories with the given discovery options.
+func (r *Registry) Scan(ctx context.Context, opts DiscoveryOptions) (int, error) {
+	defer r.Metrics.ObserveScan("scan", time.Now())
+	found, err := Ask(ctx, r.RegistryActor.Mailbox, ScanDir{Directory: r.Config.ProjectsPath, Options: opts})
+	if err != nil && !errors.Is(err, ErrRepoExists) {
+		return 0, err
+	}
+	// The scan queued its AddRepo messages ahead of this one.
+	if _, err := Ask(ctx, r.RegistryActor.Mailbox, Sync{}); err != nil {
+		return found.(int), err
+	}
+	r.resolveDependencies()
+	return found.(int), errors.Join(err, r.saveState())
+}
+
+// ToggleRepo flips a repository's active state and returns the new state.
+func (r *Registry) ToggleRepo(ctx context.Context, name string) (ToggleResult, error) {
+	result, err := Ask(ctx, r.RegistryActor.Mailbox, ToggleRepo{Name: name})
+	if err != nil {
+		return ToggleResult{}, err
+	}
+	return result.(ToggleResult), nil
+}
+
+// RefreshStatus re-reads a repository's Git status.
+func (r *Registry) RefreshStatus(ctx context.Context, name string) (GitStatus, error) {
+	result, err := Ask(ctx, r.RegistryActor.Mailbox, RefreshStatus{Name: name})
+	if err != nil {
+		return GitStatus{}, err
+	}
+	return result.(GitStatus), nil
+}
+
+// RefreshAllStatus re-reads the Git status of every repository in parallel.
+// It returns the repositories whose status could not be read.
+func (r *Registry) RefreshAllStatus(ctx context.Context) map[string]error {
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs = make(map[string]error)
+	)
+	for _, name := range r.RegistryActor.Names() {
+		repo, err := r.RegistryActor.lookup(name)
+		if err != nil {
+			continue
+		}
+		wg.Add(1)
+		go func(repo *RepoActor) {
+			defer wg.Done()
+			// Ask the RepoActors directly so they refresh concurrently.
+			if _, err := Ask(ctx, repo.Mailbox, RefreshStatus{Name: repo.Name}); err != nil {
+				mu.Lock()
+				errs[repo.Name] = err
+				mu.Unlock()
+			}
+		}(repo)
+	}
+	wg.Wait()
+	return errs
+}
+
+// ConfigureRepo adds Docker and pipeline scaffolding to a repository.
+func (r *Registry) ConfigureRepo(ctx context.Context, name string) (ConfigureResult, error) {
+	result, err := Ask(ctx, r.RegistryActor.Mailbox, ConfigureRepo{Name: name})
+	if err != nil {
+		return ConfigureResult{}, err
+	}
+	return result.(ConfigureResult), nil
+}
+
+// AddDependency records that repo depends on dependsOn. It fails with
+// ErrDependencyCycle if the change would make the graph cyclic.
+func (r *Registry) AddDependency(repo string, dependsOn []string) error {
+	if err := r.Coordinator.AddDependency(repo, dependsOn); err != nil {
+		return err
+	}
+	return r.saveState()
+}
+
+// RunGraph starts running opts.Task over the dependency graph, dependencies
+// first, and returns the Run to wait on.
+func (r *Registry) RunGraph(ctx context.Context, opts RunOptions) (*Run, error) {
+	result, err := Ask(ctx, r.Coordinator.Mailbox, StartRun{Options: opts})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*Run), nil
+}
+
 // loadConfig loads configuration settings. Replace this with actual config loading logic as needed.
 func loadConfig() (*Config, error) {
 	// Simulating config loading using hardcoded values for simplicity.
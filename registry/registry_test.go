@@ -2,115 +2,110 @@
 package registry
 
 import (
-    "context"
-    "testing"
-    "time"
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
 )
 
 func TestRegistryInitialization(t *testing.T) {
-    registry, err := NewRegistry(
-        WithProjectsPath("/tmp/test-projects"),
-        WithDockerHost("unix:///var/run/docker.sock"),
-        WithLogLevel("debug"),
-    )
-    if err != nil {
-        t.Fatalf("Failed to initialize registry: %v", err)
-    }
+	projects := t.TempDir()
+	registry, err := NewRegistry(
+		WithProjectsPath(projects),
+		WithDockerHost("unix:///var/run/docker.sock"),
+		WithLogLevel("debug"),
+		WithStatePath(""),
+	)
+	if err != nil {
+		t.Fatalf("Failed to initialize registry: %v", err)
+	}
+	defer registry.Shutdown(context.Background())
 
-    if registry.Config.ProjectsPath != "/tmp/test-projects" {
-        t.Errorf("Expected ProjectsPath to be '/tmp/test-projects', got '%s'", registry.Config.ProjectsPath)
-    }
-    // Additional tests...
+	if registry.Config.ProjectsPath != projects {
+		t.Errorf("Expected ProjectsPath to be '%s', got '%s'", projects, registry.Config.ProjectsPath)
+	}
+	if registry.Config.LogLevel != "debug" {
+		t.Errorf("Expected LogLevel to be 'debug', got '%s'", registry.Config.LogLevel)
+	}
+	if items := registry.ListItems(); len(items) != 0 {
+		t.Errorf("Expected an empty registry, got %d items", len(items))
+	}
 }
 
 func TestRegistryBasicOperations(t *testing.T) {
-    // Initialize registry with test configuration
-    registry := NewRegistry[string, string](
-        WithTTL(time.Hour),
-        WithMaxItems(100),
-    )
-
-    // Test context
-    ctx := context.Background()
-
-    // Test cases
-    tests := []struct {
-        name    string
-        key     string
-        value   string
-        wantErr bool
-    }{
-        {
-            name:    "Set and get basic item",
-            key:     "test-key",
-            value:   "test-value",
-            wantErr: false,
-        },
-        {
-            name:    "Get non-existent item",
-            key:     "non-existent",
-            value:   "",
-            wantErr: true,
-        },
-    }
+	projects := t.TempDir()
+	for _, name := range []string{"api", "web"} {
+		if _, err := git.PlainInit(filepath.Join(projects, name), false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	registry, err := NewRegistry(WithProjectsPath(projects), WithStatePath(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer registry.Shutdown(context.Background())
 
-    for _, tt := range tests {
-        t.Run(tt.name, func(t *testing.T) {
-            // Test Set operation
-            if tt.value != "" {
-                err := registry.Set(ctx, tt.key, tt.value)
-                if err != nil && !tt.wantErr {
-                    t.Errorf("Set() error = %v, wantErr %v", err, tt.wantErr)
-                    return
-                }
-            }
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 
-            // Test Get operation
-            got, err := registry.Get(ctx, tt.key)
-            if (err != nil) != tt.wantErr {
-                t.Errorf("Get() error = %v, wantErr %v", err, tt.wantErr)
-                return
-            }
+	tests := []struct {
+		name    string
+		repo    string
+		wantErr error
+	}{
+		{"Get a discovered repository", "api", nil},
+		{"Get a non-existent repository", "missing", ErrRepoNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item, err := registry.Item(tt.repo)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Item() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && item.Name != tt.repo {
+				t.Errorf("Item() got = %v, want %v", item.Name, tt.repo)
+			}
+		})
+	}
 
-            // Verify value if no error expected
-            if !tt.wantErr && got.Value != tt.value {
-                t.Errorf("Get() got = %v, want %v", got.Value, tt.value)
-            }
-        })
-    }
+	before, _ := registry.Item("web")
+	result, err := registry.ToggleRepo(ctx, "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after, _ := registry.Item("web"); result.Active == before.Enabled || after.Enabled != result.Active {
+		t.Errorf("Expected toggling to flip 'web' from %v, got %+v", before.Enabled, result)
+	}
 }
 
-func TestRegistryEventSubscription(t *testing.T) {
-    registry := NewRegistry[string, string]()
-    ctx := context.Background()
+func TestRegistryInitializationFailureReleasesResources(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
 
-    // Create channel to receive events
-    events := make(chan Event[string], 1)
-    
-    // Subscribe to registry events
-    registry.Subscribe(func(e Event[string]) {
-        events <- e
-    })
-
-    // Set a value to trigger an event
-    testKey := "event-test"
-    testValue := "test-value"
-    
-    err := registry.Set(ctx, testKey, testValue)
-    if err != nil {
-        t.Fatalf("Failed to set value: %v", err)
-    }
-
-    // Wait for event with timeout
-    select {
-    case event := <-events:
-        if event.Type != EventCreated {
-            t.Errorf("Expected event type %v, got %v", EventCreated, event.Type)
-        }
-        if event.Key != testKey {
-            t.Errorf("Expected key %v, got %v", testKey, event.Key)
-        }
-    case <-time.After(time.Second):
-        t.Error("Timeout waiting for event")
-    }
-}
\ No newline at end of file
+	// Discovery fails after the metrics server is serving.
+	missing := filepath.Join(t.TempDir(), "missing")
+	if _, err := NewRegistry(WithProjectsPath(missing), WithStatePath(""), WithMetricsAddr(addr)); err == nil {
+		t.Fatal("Expected discovering a missing projects directory to fail")
+	}
+	// The metrics server closes in the background once cancelled.
+	deadline := time.Now().Add(time.Second)
+	for {
+		listener, err = net.Listen("tcp", addr)
+		if err == nil {
+			listener.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the metrics address to be released: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
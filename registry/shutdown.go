@@ -0,0 +1,109 @@
+// File: registry/shutdown.go
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrShutdown is returned for work abandoned because the registry shut down.
+var ErrShutdown = errors.New("registry is shutting down")
+
+// Shutdown stops the registry. It stops the watcher and accepting messages,
+// lets the RegistryActor drain its mailbox, cancels in-flight operations such
+// as runs and Docker builds, then closes the RepoActors in reverse dependency
+// order so dependents stop before what they depend on. It returns once everything has
+// stopped and the state is saved, or with ctx's error when its deadline
+// expires first. Calling it again returns the first result.
+func (r *Registry) Shutdown(ctx context.Context) error {
+	r.shutdownOnce.Do(func() {
+		r.shutdownErr = r.shutdown(ctx)
+	})
+	return r.shutdownErr
+}
+
+func (r *Registry) shutdown(ctx context.Context) error {
+	// Whatever happens, nothing may keep running past Shutdown.
+	defer r.cancel()
+
+	fmt.Println("Shutting down registry...")
+	if err := r.stopWatcher(ctx); err != nil {
+		return fmt.Errorf("failed to stop watcher: %w", err)
+	}
+	r.RegistryActor.Mailbox.Close()
+	if err := waitDone(ctx, r.RegistryActor.Done()); err != nil {
+		return fmt.Errorf("failed to drain registry mailbox: %w", err)
+	}
+
+	r.cancel()
+	r.Coordinator.Mailbox.Close()
+	if err := waitDone(ctx, r.Coordinator.Done()); err != nil {
+		return fmt.Errorf("failed to drain coordinator mailbox: %w", err)
+	}
+
+	order, err := r.Coordinator.Order(r.RegistryActor.Names())
+	if err != nil {
+		// The graph never holds a cycle, but closing in any order is better
+		// than not closing at all.
+		order = r.RegistryActor.Names()
+	}
+	for i, j := 0, len(order)-1; i < j; i, j = i+1, j-1 {
+		order[i], order[j] = order[j], order[i]
+	}
+	if err := r.RegistryActor.CloseRepos(ctx, order); err != nil {
+		return err
+	}
+
+	// Coordinator tasks and restarted actors still hold the WaitGroup.
+	if err := waitDone(ctx, waitGroupDone(r.wg)); err != nil {
+		return fmt.Errorf("failed to stop registry goroutines: %w", err)
+	}
+	if err := r.saveState(); err != nil {
+		return fmt.Errorf("failed to save registry state: %w", err)
+	}
+	fmt.Println("Registry shut down.")
+	return nil
+}
+
+// waitDone waits for done to be closed or ctx to be done.
+func waitDone(ctx context.Context, done <-chan struct{}) error {
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// waitGroupDone returns a channel closed once wg's counter reaches zero.
+func waitGroupDone(wg *sync.WaitGroup) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	return done
+}
+
+// withShutdown returns a context cancelled when either ctx or base is done,
+// so requests are aborted when the registry shuts down.
+func withShutdown(ctx, base context.Context) (context.Context, context.CancelFunc) {
+	merged, cancel := context.WithCancel(ctx)
+	if base.Done() == nil {
+		return merged, cancel
+	}
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-base.Done():
+			cancel()
+		case <-stop:
+		}
+	}()
+	return merged, func() {
+		close(stop)
+		cancel()
+	}
+}
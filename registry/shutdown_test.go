@@ -0,0 +1,85 @@
+// shutdown_test.go
+package registry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestShutdownCancelsInFlightWork(t *testing.T) {
+	projects := t.TempDir()
+	writeRepo(t, projects, "core", nil)
+	writeRepo(t, projects, "app", nil)
+
+	reg, err := NewRegistry(WithProjectsPath(projects), WithStatePath(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.AddDependency("app", []string{"core"}); err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+	task := func(ctx context.Context, repo string) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	run, err := reg.RunGraph(ctx, RunOptions{Task: task})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-started
+
+	if err := reg.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	if err := run.Wait(ctx); err == nil {
+		t.Error("Expected the interrupted run to report a failure")
+	}
+	for _, result := range run.Results() {
+		if result.Status == NodeSucceeded || result.Status == NodePending || result.Status == NodeRunning {
+			t.Errorf("Expected '%s' to be aborted, got %s", result.Name, result.Status)
+		}
+	}
+
+	if _, err := reg.AddRepo(ctx, "late", t.TempDir()); !errors.Is(err, ErrMailboxClosed) {
+		t.Errorf("Expected requests after shutdown to fail with ErrMailboxClosed, got %v", err)
+	}
+	if err := reg.Shutdown(ctx); err != nil {
+		t.Errorf("Expected a second Shutdown to return the first result, got %v", err)
+	}
+}
+
+func TestShutdownRespectsDeadline(t *testing.T) {
+	projects := t.TempDir()
+	writeRepo(t, projects, "slow", nil)
+	reg, err := NewRegistry(WithProjectsPath(projects), WithStatePath(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A task that ignores cancellation keeps Shutdown waiting for it.
+	started := make(chan struct{})
+	task := func(ctx context.Context, repo string) error {
+		close(started)
+		time.Sleep(time.Second)
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := reg.RunGraph(ctx, RunOptions{Task: task}); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+
+	deadline, cancelDeadline := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancelDeadline()
+	if err := reg.Shutdown(deadline); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected Shutdown to give up at the deadline, got %v", err)
+	}
+}
@@ -0,0 +1,157 @@
+// File: registry/store.go
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// StateVersion is the version of the on-disk state format written by this
+// build. Files with a newer version are refused rather than overwritten.
+const StateVersion = 1
+
+// State is the persisted form of the registry.
+type State struct {
+	Version      int                  `json:"version"`
+	UpdatedAt    time.Time            `json:"updated_at"`
+	JournalSeq   uint64               `json:"journal_seq"`
+	Repos        map[string]RepoState `json:"repos"`
+	Dependencies map[string][]string  `json:"dependencies,omitempty"`
+	Edges        map[string][]Edge    `json:"edges,omitempty"`
+}
+
+// RepoState is the persisted form of a RepoActor.
+type RepoState struct {
+	Name        string            `json:"name"`
+	Path        string            `json:"path"`
+	URL         string            `json:"url,omitempty"`
+	Branch      string            `json:"branch,omitempty"`
+	Kind        RepoKind          `json:"kind,omitempty"`
+	Parent      string            `json:"parent,omitempty"`
+	Active      bool              `json:"active"`
+	IsDocker    bool              `json:"is_docker"`
+	HasPipeline bool              `json:"has_pipeline"`
+	CreatedAt   time.Time         `json:"created_at"`
+	LastUpdated time.Time         `json:"last_updated"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// NewState returns an empty State at the current version.
+func NewState() *State {
+	return &State{
+		Version:      StateVersion,
+		Repos:        make(map[string]RepoState),
+		Dependencies: make(map[string][]string),
+	}
+}
+
+// StateStore reads and writes the registry state file.
+type StateStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewStateStore returns a store backed by the file at path.
+func NewStateStore(path string) *StateStore {
+	return &StateStore{path: path}
+}
+
+// DefaultStatePath returns the state file location under the XDG state
+// directory, falling back to ~/.local/state when XDG_STATE_HOME is unset.
+func DefaultStatePath() string {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "go-middleware-registry", "state.json")
+}
+
+// Path returns the location of the state file.
+func (s *StateStore) Path() string {
+	return s.path
+}
+
+// Load reads the state file. A missing file yields an empty State.
+func (s *StateStore) Load() (*State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return NewState(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file '%s': %w", s.path, err)
+	}
+
+	state := NewState()
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse state file '%s': %w", s.path, err)
+	}
+	if state.Version < 1 || state.Version > StateVersion {
+		return nil, fmt.Errorf("state file '%s' has unsupported version %d (supported: %d)", s.path, state.Version, StateVersion)
+	}
+	if state.Repos == nil {
+		state.Repos = make(map[string]RepoState)
+	}
+	if state.Dependencies == nil {
+		state.Dependencies = make(map[string][]string)
+	}
+	return state, nil
+}
+
+// Save writes state atomically: it is written to a temporary file in the same
+// directory, synced, and renamed over the previous state file.
+func (s *StateStore) Save(state *State) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state.Version = StateVersion
+	state.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create state directory '%s': %w", dir, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".state-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary state file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once the rename succeeded.
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close state file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to replace state file '%s': %w", s.path, err)
+	}
+
+	// Sync the directory so the rename itself survives a crash.
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+// store_test.go
+package registry
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+)
+
+func TestStateStoreRoundTrip(t *testing.T) {
+	store := NewStateStore(filepath.Join(t.TempDir(), "nested", "state.json"))
+
+	state := NewState()
+	state.Repos["alpha"] = RepoState{Name: "alpha", Path: "/tmp/alpha", Active: false, IsDocker: true}
+	state.Dependencies["alpha"] = []string{"base"}
+	if err := store.Save(state); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if got := loaded.Repos["alpha"]; got.Active || !got.IsDocker {
+		t.Errorf("Unexpected repo state %+v", got)
+	}
+	if deps := loaded.Dependencies["alpha"]; len(deps) != 1 || deps[0] != "base" {
+		t.Errorf("Unexpected dependencies %v", deps)
+	}
+}
+
+func TestStateStoreRejectsNewerVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte(`{"version": 99, "repos": {}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewStateStore(path).Load(); err == nil {
+		t.Error("Expected an error for a state file from a newer version")
+	}
+}
+
+func TestRegistryPersistsToggleAcrossRuns(t *testing.T) {
+	projects := t.TempDir()
+	if _, err := git.PlainInit(filepath.Join(projects, "alpha"), false); err != nil {
+		t.Fatal(err)
+	}
+	statePath := filepath.Join(t.TempDir(), "state.json")
+
+	first, err := NewRegistry(WithProjectsPath(projects), WithStatePath(statePath))
+	if err != nil {
+		t.Fatalf("Failed to initialize registry: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := first.ToggleRepo(ctx, "alpha"); err != nil {
+		t.Fatalf("ToggleRepo failed: %v", err)
+	}
+
+	second, err := NewRegistry(WithProjectsPath(projects), WithStatePath(statePath))
+	if err != nil {
+		t.Fatalf("Failed to initialize registry: %v", err)
+	}
+	for _, item := range second.ListItems() {
+		if item.Name == "alpha" && item.Enabled {
+			t.Error("Expected 'alpha' to stay disabled after reload")
+		}
+	}
+}
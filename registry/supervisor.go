@@ -0,0 +1,207 @@
+// File: registry/supervisor.go
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// ErrActorFailed is returned for messages sent to an actor that crashed too
+// often and was not restarted.
+var ErrActorFailed = errors.New("actor failed")
+
+// RestartStrategy decides which children are restarted after a crash.
+type RestartStrategy int
+
+const (
+	// OneForOne restarts only the child that crashed.
+	OneForOne RestartStrategy = iota
+	// Temporary never restarts a crashed child.
+	Temporary
+)
+
+// RestartPolicy controls how a Supervisor restarts crashed children.
+type RestartPolicy struct {
+	Strategy RestartStrategy
+	// MaxRestarts is the number of restarts allowed within Window before the
+	// supervisor gives up on a child.
+	MaxRestarts int
+	Window      time.Duration
+	// InitialBackoff is doubled after every consecutive crash up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// DefaultRestartPolicy returns the policy used when none is configured.
+func DefaultRestartPolicy() RestartPolicy {
+	return RestartPolicy{
+		Strategy:       OneForOne,
+		MaxRestarts:    5,
+		Window:         time.Minute,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     10 * time.Second,
+	}
+}
+
+// ChildState is reported to a child whenever the supervisor acts on it.
+type ChildState int
+
+const (
+	ChildRunning ChildState = iota
+	ChildRestarting
+	ChildFailed
+)
+
+// ChildSpec describes an actor loop run under a Supervisor.
+type ChildSpec struct {
+	Name string
+	// Run processes messages until the mailbox is closed. A panic inside Run
+	// is treated as a crash.
+	Run func()
+	// OnState is called after a crash with the crash error, and again with
+	// ChildRunning once the child has been restarted.
+	OnState func(state ChildState, err error)
+	// OnFailed runs in place of Run once the supervisor has given up, so the
+	// child can answer pending messages instead of leaving senders blocked.
+	OnFailed func(err error)
+}
+
+// CrashError records a panic recovered from a supervised child.
+type CrashError struct {
+	Actor  string
+	Reason interface{}
+	Stack  []byte
+}
+
+func (e *CrashError) Error() string {
+	return fmt.Sprintf("actor '%s' crashed: %v", e.Actor, e.Reason)
+}
+
+// Supervisor runs actor loops, recovering panics and restarting them
+// according to its RestartPolicy.
+type Supervisor struct {
+	policy   RestartPolicy
+	events   *EventBus
+	wg       *sync.WaitGroup
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewSupervisor initializes a new Supervisor
+func NewSupervisor(policy RestartPolicy, events *EventBus, wg *sync.WaitGroup) *Supervisor {
+	return &Supervisor{
+		policy: policy,
+		events: events,
+		wg:     wg,
+		stop:   make(chan struct{}),
+	}
+}
+
+// Stop keeps the supervisor from restarting children. A child waiting to be
+// restarted drains its mailbox with ErrShutdown instead.
+func (s *Supervisor) Stop() {
+	s.stopOnce.Do(func() { close(s.stop) })
+}
+
+// Supervise launches the child's goroutine and keeps it running until Run
+// returns normally or the restart budget is exhausted. The returned channel
+// is closed once the child's goroutine has exited.
+func (s *Supervisor) Supervise(spec ChildSpec) <-chan struct{} {
+	done := make(chan struct{})
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer close(done)
+
+		var restarts []time.Time
+		backoff := s.policy.InitialBackoff
+		for {
+			started := time.Now()
+			err := s.runProtected(spec)
+			if err == nil {
+				return
+			}
+
+			s.events.Publish(Event{Type: EventActorCrashed, Actor: spec.Name, Message: err.Error(), Err: err})
+
+			now := time.Now()
+			if now.Sub(started) > s.policy.Window {
+				// The child was healthy for a while, start backing off afresh.
+				backoff = s.policy.InitialBackoff
+			}
+			restarts = trimRestarts(restarts, now.Add(-s.policy.Window))
+			if s.policy.Strategy == Temporary || len(restarts) >= s.policy.MaxRestarts {
+				s.giveUp(spec, err)
+				return
+			}
+			restarts = append(restarts, now)
+
+			spec.notify(ChildRestarting, err)
+			select {
+			case <-time.After(backoff):
+			case <-s.stop:
+				if spec.OnFailed != nil {
+					spec.OnFailed(ErrShutdown)
+				}
+				return
+			}
+			if backoff *= 2; backoff > s.policy.MaxBackoff {
+				backoff = s.policy.MaxBackoff
+			}
+			spec.notify(ChildRunning, err)
+
+			s.events.Publish(Event{
+				Type:    EventActorRestarted,
+				Actor:   spec.Name,
+				Message: fmt.Sprintf("restarted after %d crash(es) in %s", len(restarts), s.policy.Window),
+				Err:     err,
+			})
+		}
+	}()
+	return done
+}
+
+// runProtected runs the child once, converting a panic into a CrashError.
+func (s *Supervisor) runProtected(spec ChildSpec) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = &CrashError{Actor: spec.Name, Reason: p, Stack: debug.Stack()}
+		}
+	}()
+	spec.Run()
+	return nil
+}
+
+// giveUp marks the child as failed and lets it drain its mailbox.
+func (s *Supervisor) giveUp(spec ChildSpec, err error) {
+	spec.notify(ChildFailed, err)
+	s.events.Publish(Event{
+		Type:    EventActorFailed,
+		Actor:   spec.Name,
+		Message: fmt.Sprintf("gave up after %d restart(s) in %s", s.policy.MaxRestarts, s.policy.Window),
+		Err:     err,
+	})
+	if spec.OnFailed != nil {
+		spec.OnFailed(err)
+	}
+}
+
+func (spec ChildSpec) notify(state ChildState, err error) {
+	if spec.OnState != nil {
+		spec.OnState(state, err)
+	}
+}
+
+// trimRestarts drops restart timestamps older than cutoff.
+func trimRestarts(restarts []time.Time, cutoff time.Time) []time.Time {
+	kept := restarts[:0]
+	for _, t := range restarts {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
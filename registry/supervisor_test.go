@@ -0,0 +1,79 @@
+// supervisor_test.go
+package registry
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSupervisorRestartsCrashedChild(t *testing.T) {
+	wg := &sync.WaitGroup{}
+	events := NewEventBus()
+
+	var mu sync.Mutex
+	var seen []EventType
+	events.Subscribe(func(e Event) {
+		mu.Lock()
+		seen = append(seen, e.Type)
+		mu.Unlock()
+	})
+
+	policy := DefaultRestartPolicy()
+	policy.InitialBackoff = time.Millisecond
+	supervisor := NewSupervisor(policy, events, wg)
+
+	runs := 0
+	supervisor.Supervise(ChildSpec{
+		Name: "flaky",
+		Run: func() {
+			runs++
+			if runs == 1 {
+				panic("boom")
+			}
+		},
+	})
+	wg.Wait()
+
+	if runs != 2 {
+		t.Errorf("Expected child to run twice, ran %d times", runs)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(seen) != 2 || seen[0] != EventActorCrashed || seen[1] != EventActorRestarted {
+		t.Errorf("Expected crash and restart events, got %v", seen)
+	}
+}
+
+func TestSupervisorGivesUpAfterMaxRestarts(t *testing.T) {
+	wg := &sync.WaitGroup{}
+	policy := RestartPolicy{
+		Strategy:       OneForOne,
+		MaxRestarts:    2,
+		Window:         time.Minute,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+	}
+	supervisor := NewSupervisor(policy, nil, wg)
+
+	repo := NewRepoActor("doomed", t.TempDir(), wg)
+	repo.supervisor = supervisor
+	runs := 0
+	supervisor.Supervise(ChildSpec{
+		Name: repo.Name,
+		Run: func() {
+			runs++
+			panic("always")
+		},
+		OnState:  repo.setState,
+		OnFailed: func(error) {},
+	})
+	wg.Wait()
+
+	if runs != 3 {
+		t.Errorf("Expected 1 run plus 2 restarts, got %d runs", runs)
+	}
+	if status := repo.item().Status; status != "failed: actor 'doomed' crashed: always" {
+		t.Errorf("Unexpected status %q", status)
+	}
+}
@@ -0,0 +1,246 @@
+// File: registry/timeline.go
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+// maxHistoryWalk bounds how many commits of a repository are cached.
+const maxHistoryWalk = 100000
+
+// LogEntry is a commit in the cross-repository timeline.
+type LogEntry struct {
+	Repo    string
+	Hash    string
+	Author  string
+	Email   string
+	When    time.Time
+	Subject string
+}
+
+// ShortHash returns the abbreviated commit hash.
+func (e LogEntry) ShortHash() string {
+	if len(e.Hash) > 7 {
+		return e.Hash[:7]
+	}
+	return e.Hash
+}
+
+// LogOptions filters the timeline.
+type LogOptions struct {
+	Since  time.Time // Only commits at or after Since, when set
+	Until  time.Time // Only commits before Until, when set
+	Author string    // Case-insensitive substring of the author's name or email
+	Repos  []string  // Repository names; every repository when empty
+	Limit  int       // Maximum number of entries; unlimited when zero
+}
+
+// ReadLog asks a RepoActor for the commits on its HEAD matching the filter.
+// The reply is a []LogEntry, newest first.
+type ReadLog struct {
+	Since  time.Time
+	Until  time.Time
+	Author string
+}
+
+// matches reports whether entry passes the filter.
+func (m ReadLog) matches(entry LogEntry) bool {
+	if !m.Since.IsZero() && entry.When.Before(m.Since) {
+		return false
+	}
+	if !m.Until.IsZero() && !entry.When.Before(m.Until) {
+		return false
+	}
+	if m.Author != "" {
+		author := strings.ToLower(m.Author)
+		if !strings.Contains(strings.ToLower(entry.Author), author) && !strings.Contains(strings.ToLower(entry.Email), author) {
+			return false
+		}
+	}
+	return true
+}
+
+// Log merges the histories of the selected repositories into one feed,
+// newest first. Repositories whose history cannot be read are returned in
+// the error map and left out of the feed.
+func (r *Registry) Log(ctx context.Context, opts LogOptions) ([]LogEntry, map[string]error) {
+	names := opts.Repos
+	if len(names) == 0 {
+		names = r.RegistryActor.Names()
+	}
+	msg := ReadLog{Since: opts.Since, Until: opts.Until, Author: opts.Author}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		entries []LogEntry
+		errs    = make(map[string]error)
+	)
+	for _, name := range names {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			repo, err := r.RegistryActor.lookup(name)
+			var result interface{}
+			if err == nil {
+				result, err = Ask(ctx, repo.Mailbox, msg)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs[name] = err
+				return
+			}
+			entries = append(entries, result.([]LogEntry)...)
+		}(name)
+	}
+	wg.Wait()
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].When.Equal(entries[j].When) {
+			return entries[i].When.After(entries[j].When)
+		}
+		return entries[i].Repo < entries[j].Repo
+	})
+	if opts.Limit > 0 && len(entries) > opts.Limit {
+		entries = entries[:opts.Limit]
+	}
+	return entries, errs
+}
+
+// ParseSince turns a relative age such as "2w", "3d" or "12h", or a date
+// such as "2024-01-31", into the time it refers to.
+func ParseSince(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, now.Location()); err == nil {
+		return t, nil
+	}
+	units := map[byte]time.Duration{'d': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	if unit, ok := units[value[len(value)-1]]; ok {
+		n, err := strconv.Atoi(value[:len(value)-1])
+		if err == nil && n >= 0 {
+			return now.Add(-time.Duration(n) * unit), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time '%s': use a date (2006-01-02) or an age such as 2w, 3d or 12h", value)
+}
+
+// commitHistory caches the commits reachable from a repository's HEAD so
+// the history is only walked again for new commits.
+type commitHistory struct {
+	head    plumbing.Hash
+	commits []LogEntry // Newest first
+	seen    map[plumbing.Hash]bool
+}
+
+// readLog returns the commits on HEAD matching msg, newest first.
+func (r *RepoActor) readLog(msg ReadLog) ([]LogEntry, error) {
+	r.mu.RLock()
+	repo := r.gitRepo
+	r.mu.RUnlock()
+	if repo == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotGitRepo, r.Path)
+	}
+	head, err := repo.Head()
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return []LogEntry{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HEAD of '%s': %w", r.Name, err)
+	}
+
+	// Only the actor's goroutine touches the history.
+	if r.history == nil {
+		r.history = &commitHistory{}
+	}
+	if err := r.history.update(r.Name, repo, head.Hash()); err != nil {
+		return nil, fmt.Errorf("failed to read history of '%s': %w", r.Name, err)
+	}
+
+	entries := []LogEntry{}
+	for _, entry := range r.history.commits {
+		if !msg.Since.IsZero() && entry.When.Before(msg.Since) {
+			break
+		}
+		if msg.matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// update walks the commits added since the cached HEAD. If the cached HEAD
+// is no longer an ancestor of head, the history was rewritten and is walked
+// again from scratch.
+func (h *commitHistory) update(repoName string, repo *git.Repository, head plumbing.Hash) error {
+	if h.head == head {
+		return nil
+	}
+	commit, err := repo.CommitObject(head)
+	if err != nil {
+		return err
+	}
+
+	previous := h.head
+	extends := previous.IsZero()
+	var added []LogEntry
+	iter := object.NewCommitPreorderIter(commit, h.seen, nil)
+	defer iter.Close()
+	err = iter.ForEach(func(c *object.Commit) error {
+		for _, parent := range c.ParentHashes {
+			if parent == previous {
+				extends = true
+			}
+		}
+		added = append(added, LogEntry{
+			Repo:    repoName,
+			Hash:    c.Hash.String(),
+			Author:  c.Author.Name,
+			Email:   c.Author.Email,
+			When:    c.Author.When,
+			Subject: strings.SplitN(strings.TrimSpace(c.Message), "\n", 2)[0],
+		})
+		if len(h.seen)+len(added) >= maxHistoryWalk {
+			return storer.ErrStop
+		}
+		return nil
+	})
+	// History ends early in shallow clones.
+	if err != nil && !errors.Is(err, plumbing.ErrObjectNotFound) {
+		return err
+	}
+	if !extends {
+		*h = commitHistory{}
+		return h.update(repoName, repo, head)
+	}
+
+	if h.seen == nil {
+		h.seen = make(map[plumbing.Hash]bool, len(added))
+	}
+	for _, entry := range added {
+		h.seen[plumbing.NewHash(entry.Hash)] = true
+	}
+	h.commits = append(added, h.commits...)
+	sort.SliceStable(h.commits, func(i, j int) bool {
+		return h.commits[i].When.After(h.commits[j].When)
+	})
+	h.head = head
+	return nil
+}
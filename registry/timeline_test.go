@@ -0,0 +1,111 @@
+// timeline_test.go
+package registry
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// commitAs commits a change to name by author at when.
+func commitAs(t *testing.T, repo *git.Repository, name, author string, when time.Time) plumbing.Hash {
+	t.Helper()
+	worktree, _ := repo.Worktree()
+	path := filepath.Join(worktree.Filesystem.Root(), name)
+	os.WriteFile(path, []byte(when.String()), 0644)
+	worktree.Add(name)
+	hash, err := worktree.Commit("change "+name+"\n\nbody", &git.CommitOptions{
+		Author: &object.Signature{Name: author, Email: author + "@example.com", When: when},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func subjects(entries []LogEntry) []string {
+	var out []string
+	for _, entry := range entries {
+		out = append(out, entry.Repo+":"+entry.Subject)
+	}
+	return out
+}
+
+func TestRegistryLog(t *testing.T) {
+	now := time.Now()
+	projects := t.TempDir()
+	repoA, _ := git.PlainOpen(writeRepo(t, projects, "a", nil))
+	repoB, _ := git.PlainOpen(writeRepo(t, projects, "b", nil))
+	first := commitAs(t, repoA, "old.txt", "alice", now.Add(-20*24*time.Hour))
+	commitAs(t, repoA, "bob.txt", "bob", now.Add(-3*24*time.Hour))
+	commitAs(t, repoB, "b.txt", "alice", now.Add(-24*time.Hour))
+
+	reg, err := NewRegistry(WithProjectsPath(projects), WithStatePath(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	defer reg.Shutdown(ctx)
+	os.MkdirAll(filepath.Join(projects, "plain"), 0755)
+	reg.AddRepo(ctx, "plain", filepath.Join(projects, "plain"))
+
+	since, _ := ParseSince("2w", now)
+	entries, errs := reg.Log(ctx, LogOptions{Since: since})
+	if len(errs) != 1 || errs["plain"] == nil {
+		t.Errorf("Expected only 'plain' to fail, got %v", errs)
+	}
+	got := subjects(entries)
+	if len(got) != 2 || got[0] != "b:change b.txt" || got[1] != "a:change bob.txt" {
+		t.Errorf("Expected the last two weeks newest first, got %v", got)
+	}
+
+	entries, _ = reg.Log(ctx, LogOptions{Author: "ALICE", Repos: []string{"a", "b"}, Limit: 1})
+	if got := subjects(entries); len(got) != 1 || got[0] != "b:change b.txt" {
+		t.Errorf("Expected alice's latest commit, got %v", got)
+	}
+
+	// New commits are added to the cached history.
+	commitAs(t, repoA, "new.txt", "carol", now)
+	entries, _ = reg.Log(ctx, LogOptions{Repos: []string{"a"}})
+	actor, _ := reg.RegistryActor.lookup("a")
+	if len(entries) != 3 || entries[0].Author != "carol" || len(actor.history.seen) != 3 {
+		t.Errorf("Expected the new commit on top of the cached history, got %v", subjects(entries))
+	}
+
+	// Rewriting history drops commits that are no longer reachable.
+	worktree, _ := repoA.Worktree()
+	if err := worktree.Reset(&git.ResetOptions{Commit: first, Mode: git.HardReset}); err != nil {
+		t.Fatal(err)
+	}
+	commitAs(t, repoA, "rewrite.txt", "dave", now)
+	entries, _ = reg.Log(ctx, LogOptions{Repos: []string{"a"}})
+	if got := subjects(entries); len(got) != 2 || got[0] != "a:change rewrite.txt" || got[1] != "a:change old.txt" {
+		t.Errorf("Expected the rewritten history, got %v", got)
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	for value, want := range map[string]time.Time{
+		"2w":         now.Add(-14 * 24 * time.Hour),
+		"3d":         now.Add(-3 * 24 * time.Hour),
+		"90m":        now.Add(-90 * time.Minute),
+		"2024-03-01": time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		"":           {},
+	} {
+		got, err := ParseSince(value, now)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("ParseSince(%q) = %v, %v; want %v", value, got, err, want)
+		}
+	}
+	if _, err := ParseSince("soon", now); err == nil {
+		t.Error("Expected an invalid time to fail")
+	}
+}
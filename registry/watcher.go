@@ -0,0 +1,388 @@
+// File: registry/watcher.go
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultWatchDebounce is how long the watcher waits for a burst of file
+// system events, such as a git checkout, to end before acting on it.
+const DefaultWatchDebounce = 500 * time.Millisecond
+
+// DetectFeatures asks a RepoActor to look for its Dockerfile, pipeline and
+// manifest again. The reply is true when IsDocker or HasPipeline changed.
+type DetectFeatures struct{}
+
+// watchedDir is a watched directory belonging to a repository.
+type watchedDir struct {
+	repo string
+	root bool // The repository's root rather than one of its workflow directories
+}
+
+// watchBatch is the work collected from a burst of events.
+type watchBatch struct {
+	rescan bool            // Repositories may have appeared or disappeared
+	repos  map[string]bool // Repositories whose features may have changed
+}
+
+// WatcherActor keeps the registry in sync with the projects directory. It
+// watches the directories discovery searches, to add and remove
+// repositories, and the root and workflow directories of each repository,
+// to notice a Dockerfile or workflow being created or deleted.
+type WatcherActor struct {
+	registry *Registry
+	watcher  *fsnotify.Watcher
+	debounce time.Duration
+	watched  map[string]bool
+	searched map[string]bool // Directories searched for repositories
+	repoDirs map[string]watchedDir
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// NewWatcherActor initializes a WatcherActor for the registry. Events are
+// acted on once none has arrived for debounce, DefaultWatchDebounce when
+// zero.
+func NewWatcherActor(reg *Registry, debounce time.Duration) (*WatcherActor, error) {
+	if debounce <= 0 {
+		debounce = DefaultWatchDebounce
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	return &WatcherActor{
+		registry: reg,
+		watcher:  watcher,
+		debounce: debounce,
+		watched:  make(map[string]bool),
+		searched: make(map[string]bool),
+		repoDirs: make(map[string]watchedDir),
+		cancel:   func() {},
+		done:     make(chan struct{}),
+	}, nil
+}
+
+// Start watches the projects directory and launches the WatcherActor's
+// goroutine, which runs until ctx is cancelled or Stop is called.
+func (w *WatcherActor) Start(ctx context.Context) error {
+	if err := w.search(); err != nil {
+		w.watcher.Close()
+		close(w.done)
+		return err
+	}
+	w.sync()
+
+	ctx, w.cancel = context.WithCancel(ctx)
+	w.registry.wg.Add(1)
+	go func() {
+		defer w.registry.wg.Done()
+		defer close(w.done)
+		defer w.watcher.Close()
+		w.loop(ctx)
+	}()
+	return nil
+}
+
+// Stop stops the WatcherActor. Done is closed once it has stopped.
+func (w *WatcherActor) Stop() {
+	w.cancel()
+}
+
+// Done is closed once the WatcherActor has stopped.
+func (w *WatcherActor) Done() <-chan struct{} {
+	return w.done
+}
+
+// loop collects events until the watcher has been quiet for the debounce
+// interval, then applies them.
+func (w *WatcherActor) loop(ctx context.Context) {
+	timer := time.NewTimer(w.debounce)
+	timer.Stop()
+	defer timer.Stop()
+	batch := watchBatch{repos: make(map[string]bool)}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if !w.classify(event, &batch) {
+				continue
+			}
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			fmt.Printf("Watcher error: %v\n", err)
+			if !errors.Is(err, fsnotify.ErrEventOverflow) {
+				continue
+			}
+			// Events were lost; look at everything again.
+			batch.rescan = true
+			for _, dir := range w.repoDirs {
+				batch.repos[dir.repo] = true
+			}
+		case <-timer.C:
+			w.apply(ctx, batch)
+			batch = watchBatch{repos: make(map[string]bool)}
+			continue
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(w.debounce)
+	}
+}
+
+// classify adds what event calls for to batch and reports whether it
+// called for anything.
+func (w *WatcherActor) classify(event fsnotify.Event, batch *watchBatch) bool {
+	if event.Op == fsnotify.Chmod {
+		return false
+	}
+	dir, base := filepath.Dir(event.Name), filepath.Base(event.Name)
+	if watched, ok := w.repoDirs[dir]; ok {
+		if !watched.root {
+			batch.repos[watched.repo] = true
+			return true
+		}
+		switch base {
+		case ".git":
+			batch.rescan = true
+			batch.repos[watched.repo] = true
+			return true
+		case "Dockerfile", ".github", ManifestFile:
+			batch.repos[watched.repo] = true
+			return true
+		}
+		return false
+	}
+	if w.searched[dir] || w.searched[event.Name] {
+		batch.rescan = true
+		return true
+	}
+	return false
+}
+
+// apply adds and removes repositories and re-detects features as batch
+// calls for, then updates what is watched.
+func (w *WatcherActor) apply(ctx context.Context, batch watchBatch) {
+	changed := false
+	if batch.rescan {
+		changed = w.rescan(ctx)
+	}
+	for name := range batch.repos {
+		repo, err := w.registry.RegistryActor.lookup(name)
+		if err != nil {
+			continue
+		}
+		askCtx, cancel := context.WithTimeout(ctx, DefaultAskTimeout)
+		result, err := Ask(askCtx, repo.Mailbox, DetectFeatures{})
+		cancel()
+		if err != nil {
+			fmt.Printf("Error detecting changes in '%s': %v\n", name, err)
+			continue
+		}
+		if result.(bool) {
+			changed = true
+			w.registry.RegistryActor.Events.Publish(Event{Type: EventRepoChanged, Actor: name, Message: repo.Path})
+		}
+	}
+	if changed {
+		w.registry.resolveDependencies()
+		if err := w.registry.saveState(); err != nil {
+			fmt.Printf("Error saving registry state: %v\n", err)
+		}
+	}
+	w.sync()
+}
+
+// rescan adds the repositories that appeared under the projects directory
+// and removes those whose Git directory disappeared. It reports whether
+// the registry changed.
+func (w *WatcherActor) rescan(ctx context.Context) bool {
+	config := w.registry.Config
+	opts := config.Discovery
+	opts.Progress = nil
+	discovered, searched, err := discover(config.ProjectsPath, opts)
+	if err != nil {
+		fmt.Printf("Error scanning '%s': %v\n", config.ProjectsPath, err)
+		return false
+	}
+	w.setSearched(searched)
+
+	changed := false
+	for _, repo := range discovered {
+		if w.registry.RegistryActor.registeredAt(repo.Path) != nil {
+			continue
+		}
+		if existing, err := w.registry.RegistryActor.lookup(repo.Name); err == nil {
+			fmt.Printf("Repository at '%s' not added: the name '%s' is taken by '%s'.\n", repo.Path, repo.Name, existing.Path)
+			continue
+		}
+		askCtx, cancel := context.WithTimeout(ctx, DefaultAskTimeout)
+		_, err := Ask(askCtx, w.registry.RegistryActor.Mailbox, AddRepo{Name: repo.Name, Path: repo.Path, Kind: repo.Kind, Parent: repo.Parent})
+		cancel()
+		if err != nil {
+			fmt.Printf("Error adding repository '%s': %v\n", repo.Name, err)
+			continue
+		}
+		changed = true
+		fmt.Printf("Repository '%s' appeared and was added to the registry.\n", repo.Name)
+		w.registry.RegistryActor.Events.Publish(Event{Type: EventRepoAdded, Actor: repo.Name, Message: repo.Path})
+	}
+
+	for _, name := range w.registry.RegistryActor.Names() {
+		repo, err := w.registry.RegistryActor.lookup(name)
+		if err != nil || !within(config.ProjectsPath, repo.Path) {
+			continue
+		}
+		// Leave repositories that were never opened alone, such as clones
+		// still in progress.
+		repo.mu.RLock()
+		opened := repo.gitRepo != nil
+		repo.mu.RUnlock()
+		if _, err := os.Lstat(filepath.Join(repo.Path, ".git")); !opened || !os.IsNotExist(err) {
+			continue
+		}
+		askCtx, cancel := context.WithTimeout(ctx, DefaultAskTimeout)
+		_, err = Ask(askCtx, w.registry.RegistryActor.Mailbox, RemoveRepo{Name: name})
+		cancel()
+		if err != nil {
+			fmt.Printf("Error removing repository '%s': %v\n", name, err)
+			continue
+		}
+		changed = true
+		w.registry.RegistryActor.Events.Publish(Event{Type: EventRepoRemoved, Actor: name, Message: repo.Path})
+	}
+	return changed
+}
+
+// search records the directories discovery searches.
+func (w *WatcherActor) search() error {
+	opts := w.registry.Config.Discovery
+	opts.Progress = nil
+	_, searched, err := discover(w.registry.Config.ProjectsPath, opts)
+	if err != nil {
+		return err
+	}
+	w.setSearched(searched)
+	return nil
+}
+
+func (w *WatcherActor) setSearched(dirs []string) {
+	w.searched = make(map[string]bool, len(dirs))
+	for _, dir := range dirs {
+		w.searched[dir] = true
+	}
+}
+
+// sync watches the searched directories and the root, .github and
+// .github/workflows directories of every repository under the projects
+// directory, and stops watching anything else.
+func (w *WatcherActor) sync() {
+	want := make(map[string]bool, len(w.searched))
+	for dir := range w.searched {
+		want[dir] = true
+	}
+	repoDirs := make(map[string]watchedDir)
+	for _, name := range w.registry.RegistryActor.Names() {
+		repo, err := w.registry.RegistryActor.lookup(name)
+		if err != nil || !within(w.registry.Config.ProjectsPath, repo.Path) {
+			continue
+		}
+		dirs := []string{repo.Path, filepath.Join(repo.Path, ".github"), filepath.Join(repo.Path, ".github", "workflows")}
+		for i, dir := range dirs {
+			if info, err := os.Stat(dir); err == nil && info.IsDir() {
+				want[dir] = true
+				repoDirs[dir] = watchedDir{repo: name, root: i == 0}
+			}
+		}
+	}
+
+	for dir := range w.watched {
+		if !want[dir] {
+			// Deleted directories are no longer watched anyway.
+			w.watcher.Remove(dir)
+			delete(w.watched, dir)
+		}
+	}
+	for dir := range want {
+		if w.watched[dir] {
+			continue
+		}
+		if err := w.watcher.Add(dir); err != nil {
+			fmt.Printf("Error watching '%s': %v\n", dir, err)
+			continue
+		}
+		w.watched[dir] = true
+	}
+	w.repoDirs = repoDirs
+}
+
+// detectFeatures re-detects the repository's Dockerfile, pipeline and
+// manifest and reports whether IsDocker or HasPipeline changed.
+func (r *RepoActor) detectFeatures() bool {
+	r.mu.RLock()
+	isDocker, hasPipeline := r.IsDocker, r.HasPipeline
+	r.mu.RUnlock()
+
+	r.detect()
+
+	r.mu.Lock()
+	changed := r.IsDocker != isDocker || r.HasPipeline != hasPipeline
+	if changed {
+		r.LastUpdated = time.Now()
+	}
+	isDocker, hasPipeline = r.IsDocker, r.HasPipeline
+	r.mu.Unlock()
+	if changed {
+		fmt.Printf("Repo '%s' changed: Docker %v, Pipeline %v\n", r.Name, isDocker, hasPipeline)
+	}
+	return changed
+}
+
+// Watch starts a WatcherActor on the projects directory so repositories
+// are added, removed and re-detected as they change on disk. It does
+// nothing when the registry is already watching.
+func (r *Registry) Watch(debounce time.Duration) error {
+	r.watchMu.Lock()
+	defer r.watchMu.Unlock()
+	if r.watcher != nil {
+		return nil
+	}
+	watcher, err := NewWatcherActor(r, debounce)
+	if err != nil {
+		return err
+	}
+	if err := watcher.Start(r.ctx); err != nil {
+		return fmt.Errorf("failed to watch '%s': %w", r.Config.ProjectsPath, err)
+	}
+	r.watcher = watcher
+	return nil
+}
+
+// stopWatcher stops the WatcherActor, if any, and waits for it.
+func (r *Registry) stopWatcher(ctx context.Context) error {
+	r.watchMu.Lock()
+	watcher := r.watcher
+	r.watchMu.Unlock()
+	if watcher == nil {
+		return nil
+	}
+	watcher.Stop()
+	return waitDone(ctx, watcher.Done())
+}
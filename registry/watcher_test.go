@@ -0,0 +1,120 @@
+// watcher_test.go
+package registry
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatcher(t *testing.T) {
+	projects := t.TempDir()
+	api := writeRepo(t, projects, "api", nil)
+	reg, err := NewRegistry(WithProjectsPath(projects), WithStatePath(""), WithWatch(50*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		reg.Shutdown(ctx)
+	}()
+
+	events := make(chan Event, 100)
+	reg.Subscribe(func(e Event) {
+		select {
+		case events <- e:
+		default:
+		}
+	})
+	expect := func(typ EventType, actor string) {
+		t.Helper()
+		timeout := time.After(5 * time.Second)
+		for {
+			select {
+			case e := <-events:
+				if e.Type == typ && e.Actor == actor {
+					return
+				}
+			case <-timeout:
+				t.Fatalf("Expected %s for '%s'", typ, actor)
+			}
+		}
+	}
+	features := func(name string) (bool, bool) {
+		t.Helper()
+		repo, err := reg.RegistryActor.lookup(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		repo.mu.RLock()
+		defer repo.mu.RUnlock()
+		return repo.IsDocker, repo.HasPipeline
+	}
+
+	writeRepo(t, filepath.Join(projects, "services"), "web", nil)
+	expect(EventRepoAdded, "services/web")
+	if _, err := reg.Item("services/web"); err != nil {
+		t.Errorf("Expected 'services/web' to be registered: %v", err)
+	}
+
+	os.WriteFile(filepath.Join(api, "Dockerfile"), []byte("FROM scratch\n"), 0644)
+	expect(EventRepoChanged, "api")
+	os.MkdirAll(filepath.Join(api, ".github", "workflows"), 0755)
+	expect(EventRepoChanged, "api")
+	if isDocker, hasPipeline := features("api"); !isDocker || !hasPipeline {
+		t.Errorf("Expected a Dockerfile and pipeline, got %v and %v", isDocker, hasPipeline)
+	}
+
+	// A burst that ends where it started changes nothing.
+	for i := 0; i < 10; i++ {
+		os.Remove(filepath.Join(api, "Dockerfile"))
+		os.WriteFile(filepath.Join(api, "Dockerfile"), []byte("FROM scratch\n"), 0644)
+	}
+	time.Sleep(300 * time.Millisecond)
+	select {
+	case e := <-events:
+		t.Errorf("Expected no event after a burst, got %+v", e)
+	default:
+	}
+
+	os.RemoveAll(filepath.Join(projects, "services"))
+	expect(EventRepoRemoved, "services/web")
+	if _, err := reg.Item("services/web"); err == nil {
+		t.Error("Expected 'services/web' to be removed")
+	}
+}
+
+func TestWatcherKeepsToggle(t *testing.T) {
+	projects := t.TempDir()
+	api := writeRepo(t, projects, "api", map[string]string{ManifestFile: "enabled: true\n"})
+	reg, err := NewRegistry(WithProjectsPath(projects), WithStatePath(""), WithWatch(50*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	defer reg.Shutdown(ctx)
+
+	changed := make(chan struct{}, 10)
+	reg.Subscribe(func(e Event) {
+		if e.Type == EventRepoChanged && e.Actor == "api" {
+			changed <- struct{}{}
+		}
+	})
+	if result, err := reg.ToggleRepo(ctx, "api"); err != nil || result.Active {
+		t.Fatalf("Expected toggling to deactivate 'api', got %+v, %v", result, err)
+	}
+
+	os.WriteFile(filepath.Join(api, "Dockerfile"), []byte("FROM scratch\n"), 0644)
+	select {
+	case <-changed:
+	case <-ctx.Done():
+		t.Fatal("Expected repo.changed for 'api'")
+	}
+	if item, _ := reg.Item("api"); item.Enabled {
+		t.Error("Expected the toggle to survive an unrelated edit")
+	}
+}